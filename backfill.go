@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/services"
+	"github.com/sirupsen/logrus"
+)
+
+// backfill re-syncs an explicit block range without moving the live height cursor
+func backfill(ctx context.Context, syncer *services.DataSync, args []string) error {
+	var FromHeight, ToHeight uint64
+
+	flagset := flag.NewFlagSet("backfill", flag.ExitOnError)
+	flagset.Uint64Var(&FromHeight, "from", 0, "the start height to backfill")
+	flagset.Uint64Var(&ToHeight, "to", 0, "the end height to backfill, inclusive")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if ToHeight == 0 || FromHeight > ToHeight {
+		return fmt.Errorf("invalid backfill range: %d-%d", FromHeight, ToHeight)
+	}
+
+	res, err := syncer.Backfill(ctx, FromHeight, ToHeight)
+	if res != nil {
+		logrus.Infof("Backfill deposits: New %d Existing %d Changed %d", res.Deposits.New, res.Deposits.Existing, res.Deposits.Changed)
+		logrus.Infof("Backfill withdrawals: New %d Existing %d Changed %d", res.Withdrawals.New, res.Withdrawals.Existing, res.Withdrawals.Changed)
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+/// @title Disperse
+/// @notice Sends the native token to many recipients in one transaction for the batch drips of the faucet.
+/// A failed transfer doesn't revert the batch, it emits DisperseFailed and its value is refunded to the sender.
+/// The abi is in abis/Disperse.json and the go binding in internal/goabi/Disperse.go.
+contract Disperse {
+    /// @dev the gas forwarded to a recipient, the drips only go to EOAs so the stipend is enough
+    uint256 private constant TRANSFER_GAS = 2300;
+
+    event Dispersed(address indexed recipient, uint256 amount);
+    event DisperseFailed(address indexed recipient, uint256 amount);
+
+    function disperseEther(address[] calldata recipients, uint256[] calldata values) external payable {
+        require(recipients.length == values.length, "Disperse: length mismatch");
+
+        uint256 sent;
+        for (uint256 i = 0; i < recipients.length; i++) {
+            (bool ok, ) = payable(recipients[i]).call{value: values[i], gas: TRANSFER_GAS}("");
+            if (ok) {
+                sent += values[i];
+                emit Dispersed(recipients[i], values[i]);
+            } else {
+                emit DisperseFailed(recipients[i], values[i]);
+            }
+        }
+
+        // the failed transfers and the value left over go back to the sender, it reverts if the value is not enough
+        uint256 refund = msg.value - sent;
+        if (refund > 0) {
+            (bool ok, ) = payable(msg.sender).call{value: refund}("");
+            require(ok, "Disperse: refund failed");
+        }
+    }
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+// decisions prints the drip decisions of a deposit txid or an address as json lines, the latest first
+func decisions(ctx context.Context, repo repository.Metis, args []string) error {
+	var (
+		Txid    string
+		Address string
+		Limit   int
+	)
+
+	flagset := flag.NewFlagSet("decisions", flag.ExitOnError)
+	flagset.StringVar(&Txid, "txid", "", "the layer1 deposit txid")
+	flagset.StringVar(&Address, "address", "", "the layer1 sender or the layer2 recipient address")
+	flagset.IntVar(&Limit, "limit", 20, "max decisions to print")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if Txid == "" && Address == "" {
+		return errors.New("either -txid or -address is required")
+	}
+
+	res, err := repo.GetDecisions(ctx, strings.ToLower(Txid), strings.ToLower(Address), Limit)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, item := range res {
+		record := struct {
+			*repository.Decision
+			Checks json.RawMessage `json:"checks"`
+		}{item, item.Checks}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
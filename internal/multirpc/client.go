@@ -0,0 +1,370 @@
+package multirpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// the max blocks an endpoint can lag behind the highest one to be healthy
+const MaxLagBlocks = 5
+
+var ErrNoQuorum = errors.New("multirpc: no quorum")
+
+type endpoint struct {
+	url     string
+	client  *ethclient.Client
+	healthy atomic.Bool
+}
+
+// Client is an ethereum rpc client over several endpoints,
+// it fails over to the next endpoint on errors and optionally requires
+// the agreement from a quorum of endpoints on logs and block headers.
+type Client struct {
+	endpoints []*endpoint
+	quorum    int
+}
+
+func Dial(urls []string, quorum int) (*Client, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("multirpc: no endpoint")
+	}
+	if quorum > len(urls) {
+		return nil, fmt.Errorf("multirpc: quorum %d is greater than the endpoint count %d", quorum, len(urls))
+	}
+
+	c := &Client{quorum: quorum}
+	for _, url := range urls {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			logrus.Errorf("multirpc: unable to connect to %s: %s", url, err)
+			continue
+		}
+		c.endpoints = append(c.endpoints, newEndpoint(url, client))
+	}
+	if len(c.endpoints) == 0 {
+		return nil, errors.New("multirpc: unable to connect to any endpoint")
+	}
+	// the quorum can't be reached if too many endpoints are down at startup
+	if quorum > len(c.endpoints) {
+		c.Close()
+		return nil, fmt.Errorf("multirpc: quorum %d is greater than the connected endpoint count %d", quorum, len(c.endpoints))
+	}
+	return c, nil
+}
+
+func newEndpoint(url string, client *ethclient.Client) *endpoint {
+	ep := &endpoint{url: url, client: client}
+	ep.healthy.Store(true)
+	return ep
+}
+
+func (c *Client) Close() {
+	for _, ep := range c.endpoints {
+		ep.client.Close()
+	}
+}
+
+// Monitor checks the endpoints health periodically until the context is done
+func (c *Client) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.checkHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) checkHealth(basectx context.Context) {
+	newctx, cancel := context.WithTimeout(basectx, time.Second*5)
+	defer cancel()
+
+	heights := make([]uint64, len(c.endpoints))
+	errs := make([]error, len(c.endpoints))
+	var wg sync.WaitGroup
+	for idx, ep := range c.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			heights[idx], errs[idx] = ep.client.BlockNumber(newctx)
+		}()
+	}
+	wg.Wait()
+
+	var highest uint64
+	for idx := range c.endpoints {
+		if errs[idx] == nil {
+			highest = max(highest, heights[idx])
+		}
+	}
+
+	for idx, ep := range c.endpoints {
+		healthy := errs[idx] == nil && heights[idx]+MaxLagBlocks >= highest
+		if ep.healthy.Swap(healthy) != healthy {
+			if healthy {
+				logrus.Infof("multirpc: %s is healthy now", ep.url)
+			} else {
+				logrus.Warnf("multirpc: %s is unhealthy: Height %d Highest %d Error %v", ep.url, heights[idx], highest, errs[idx])
+			}
+		}
+	}
+}
+
+// candidates returns the healthy endpoints first, and the unhealthy ones as the last resort
+func (c *Client) candidates() []*endpoint {
+	var healthy, unhealthy []*endpoint
+	for _, ep := range c.endpoints {
+		if ep.healthy.Load() {
+			healthy = append(healthy, ep)
+		} else {
+			unhealthy = append(unhealthy, ep)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// shouldFailover reports whether the error is caused by the endpoint rather than the request
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	// the json-rpc error is returned by the node, other endpoints would return the same one
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+// call runs fn against the endpoints in order until one of them succeeds
+func call[T any](ctx context.Context, c *Client, method string, fn func(*ethclient.Client) (T, error)) (T, error) {
+	var lastErr error
+	for _, ep := range c.candidates() {
+		res, err := fn(ep.client)
+		if err == nil || !shouldFailover(ctx, err) {
+			return res, err
+		}
+		logrus.Warnf("multirpc: %s failed on %s: %s", method, ep.url, err)
+		ep.healthy.Store(false)
+		lastErr = err
+	}
+	var zero T
+	return zero, fmt.Errorf("multirpc: %s failed on all endpoints: %w", method, lastErr)
+}
+
+// quorumCall runs fn against the endpoints concurrently,
+// and returns the result which at least quorum endpoints agree on.
+func quorumCall[T any](ctx context.Context, c *Client, method string, fn func(*ethclient.Client) (T, error), digest func(T) string) (T, error) {
+	var zero T
+	if c.quorum <= 1 {
+		return call(ctx, c, method, fn)
+	}
+
+	endpoints := c.candidates()
+	results := make([]T, len(endpoints))
+	errs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for idx, ep := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[idx], errs[idx] = fn(ep.client)
+		}()
+	}
+	wg.Wait()
+
+	var (
+		votes    = make(map[string]int)
+		firstErr error
+	)
+	for idx, ep := range endpoints {
+		if err := errs[idx]; err != nil {
+			if shouldFailover(ctx, err) {
+				logrus.Warnf("multirpc: %s failed on %s: %s", method, ep.url, err)
+				ep.healthy.Store(false)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		key := digest(results[idx])
+		if votes[key]++; votes[key] >= c.quorum {
+			return results[idx], nil
+		}
+	}
+
+	if firstErr != nil {
+		return zero, fmt.Errorf("multirpc: %s: %w: %w", method, ErrNoQuorum, firstErr)
+	}
+	return zero, fmt.Errorf("multirpc: %s: %w: the endpoints disagree", method, ErrNoQuorum)
+}
+
+// subscribe runs fn against the endpoints in order until one of them supports the subscription
+func subscribe(ctx context.Context, c *Client, method string, fn func(*ethclient.Client) (ethereum.Subscription, error)) (ethereum.Subscription, error) {
+	var lastErr error
+	for _, ep := range c.candidates() {
+		sub, err := fn(ep.client)
+		if err == nil {
+			return sub, nil
+		}
+		if !errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			if !shouldFailover(ctx, err) {
+				return nil, err
+			}
+			logrus.Warnf("multirpc: %s failed on %s: %s", method, ep.url, err)
+			ep.healthy.Store(false)
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("multirpc: %s failed on all endpoints: %w", method, lastErr)
+}
+
+func headerDigest(header *types.Header) string {
+	return header.Hash().Hex()
+}
+
+func blockDigest(block *types.Block) string {
+	return block.Hash().Hex()
+}
+
+func logsDigest(logs []types.Log) string {
+	var buf []byte
+	for _, item := range logs {
+		buf = fmt.Appendf(buf, "%s:%s:%d:%t;", item.BlockHash.Hex(), item.TxHash.Hex(), item.Index, item.Removed)
+	}
+	return string(buf)
+}
+
+func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	return call(ctx, c, "ChainID", func(client *ethclient.Client) (*big.Int, error) {
+		return client.ChainID(ctx)
+	})
+}
+
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	return call(ctx, c, "BlockNumber", func(client *ethclient.Client) (uint64, error) {
+		return client.BlockNumber(ctx)
+	})
+}
+
+func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return quorumCall(ctx, c, "HeaderByNumber", func(client *ethclient.Client) (*types.Header, error) {
+		return client.HeaderByNumber(ctx, number)
+	}, headerDigest)
+}
+
+func (c *Client) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return quorumCall(ctx, c, "BlockByNumber", func(client *ethclient.Client) (*types.Block, error) {
+		return client.BlockByNumber(ctx, number)
+	}, blockDigest)
+}
+
+func (c *Client) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return quorumCall(ctx, c, "BalanceAt", func(client *ethclient.Client) (*big.Int, error) {
+		return client.BalanceAt(ctx, account, blockNumber)
+	}, (*big.Int).String)
+}
+
+func (c *Client) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return quorumCall(ctx, c, "HeaderByHash", func(client *ethclient.Client) (*types.Header, error) {
+		return client.HeaderByHash(ctx, hash)
+	}, headerDigest)
+}
+
+func (c *Client) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return quorumCall(ctx, c, "FilterLogs", func(client *ethclient.Client) ([]types.Log, error) {
+		return client.FilterLogs(ctx, q)
+	}, logsDigest)
+}
+
+func (c *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return subscribe(ctx, c, "SubscribeFilterLogs", func(client *ethclient.Client) (ethereum.Subscription, error) {
+		return client.SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return subscribe(ctx, c, "SubscribeNewHead", func(client *ethclient.Client) (ethereum.Subscription, error) {
+		return client.SubscribeNewHead(ctx, ch)
+	})
+}
+
+func (c *Client) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	type result struct {
+		tx        *types.Transaction
+		isPending bool
+	}
+	res, err := call(ctx, c, "TransactionByHash", func(client *ethclient.Client) (result, error) {
+		tx, isPending, err := client.TransactionByHash(ctx, hash)
+		return result{tx: tx, isPending: isPending}, err
+	})
+	return res.tx, res.isPending, err
+}
+
+func (c *Client) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	return call(ctx, c, "TransactionReceipt", func(client *ethclient.Client) (*types.Receipt, error) {
+		return client.TransactionReceipt(ctx, hash)
+	})
+}
+
+func (c *Client) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return call(ctx, c, "CodeAt", func(client *ethclient.Client) ([]byte, error) {
+		return client.CodeAt(ctx, account, blockNumber)
+	})
+}
+
+func (c *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return call(ctx, c, "CallContract", func(client *ethclient.Client) ([]byte, error) {
+		return client.CallContract(ctx, msg, blockNumber)
+	})
+}
+
+func (c *Client) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return call(ctx, c, "PendingCodeAt", func(client *ethclient.Client) ([]byte, error) {
+		return client.PendingCodeAt(ctx, account)
+	})
+}
+
+func (c *Client) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return call(ctx, c, "PendingNonceAt", func(client *ethclient.Client) (uint64, error) {
+		return client.PendingNonceAt(ctx, account)
+	})
+}
+
+func (c *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return call(ctx, c, "SuggestGasPrice", func(client *ethclient.Client) (*big.Int, error) {
+		return client.SuggestGasPrice(ctx)
+	})
+}
+
+func (c *Client) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return call(ctx, c, "SuggestGasTipCap", func(client *ethclient.Client) (*big.Int, error) {
+		return client.SuggestGasTipCap(ctx)
+	})
+}
+
+func (c *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return call(ctx, c, "EstimateGas", func(client *ethclient.Client) (uint64, error) {
+		return client.EstimateGas(ctx, msg)
+	})
+}
+
+func (c *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := call(ctx, c, "SendTransaction", func(client *ethclient.Client) (struct{}, error) {
+		return struct{}{}, client.SendTransaction(ctx, tx)
+	})
+	return err
+}
@@ -0,0 +1,123 @@
+package multirpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type fakeEth struct {
+	number uint64
+	logs   []types.Log
+}
+
+func (f *fakeEth) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(f.number)
+}
+
+func (f *fakeEth) GetLogs(_ map[string]interface{}) []types.Log {
+	return f.logs
+}
+
+func newFakeEndpoint(t *testing.T, url string, service *fakeEth) *endpoint {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return newEndpoint(url, ethclient.NewClient(rpc.DialInProc(server)))
+}
+
+func newDownEndpoint(t *testing.T) *endpoint {
+	client, err := ethclient.Dial("http://127.0.0.1:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newEndpoint("down", client)
+}
+
+func newLog(txid string) types.Log {
+	return types.Log{TxHash: common.HexToHash(txid), BlockHash: common.HexToHash("0x01"), Topics: []common.Hash{}, Data: []byte{}}
+}
+
+func TestClient_Failover(t *testing.T) {
+	down := newDownEndpoint(t)
+	c := &Client{endpoints: []*endpoint{down, newFakeEndpoint(t, "up", &fakeEth{number: 100})}}
+
+	got, err := c.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 100 {
+		t.Errorf("BlockNumber() = %d, want 100", got)
+	}
+	if down.healthy.Load() {
+		t.Errorf("the failed endpoint should be marked as unhealthy")
+	}
+	if c.candidates()[0] == down {
+		t.Errorf("the unhealthy endpoint should be the last resort")
+	}
+}
+
+func TestClient_Quorum(t *testing.T) {
+	logs := []types.Log{newLog("0x0a")}
+	tests := []struct {
+		name    string
+		others  []types.Log
+		quorum  int
+		wantErr bool
+	}{
+		{"agree", []types.Log{newLog("0x0a")}, 2, false},
+		{"disagree", []types.Log{newLog("0x0b")}, 2, true},
+		{"no quorum required", []types.Log{newLog("0x0b")}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{
+				quorum: tt.quorum,
+				endpoints: []*endpoint{
+					newFakeEndpoint(t, "a", &fakeEth{logs: logs}),
+					newFakeEndpoint(t, "b", &fakeEth{logs: tt.others}),
+				},
+			}
+			_, err := c.FilterLogs(context.Background(), ethereum.FilterQuery{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FilterLogs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrNoQuorum) {
+				t.Errorf("FilterLogs() error = %v, want ErrNoQuorum", err)
+			}
+		})
+	}
+}
+
+func TestDial_Quorum(t *testing.T) {
+	tests := []struct {
+		name    string
+		urls    []string
+		quorum  int
+		wantErr bool
+	}{
+		{"connected", []string{"http://127.0.0.1:1", "http://127.0.0.1:2"}, 2, false},
+		{"one endpoint unreachable", []string{"http://127.0.0.1:1", "unknown://127.0.0.1:2"}, 2, true},
+		{"quorum of the connected", []string{"http://127.0.0.1:1", "unknown://127.0.0.1:2"}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Dial(tt.urls, tt.quorum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Dial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if c != nil {
+				c.Close()
+			}
+		})
+	}
+}
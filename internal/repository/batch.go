@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewDripBatch saves the batch transaction with its drips, and the deposits are processing after it
+func (m Metis) NewDripBatch(ctx context.Context, batch *DripBatch, drips []*Drip) (err error) {
+	// the drip limits are counted after the locks are taken, so every statement reads the latest committed drips
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("NewDripBatch: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("NewDripBatch: rollback: %s", rollbackError)
+		}
+	}()
+
+	if err = lockDripLimits(ctx, tx, drips); err != nil {
+		return fmt.Errorf("NewDripBatch: %w", err)
+	}
+
+	const insertBatchQuery = "INSERT INTO `drip_batches` (`chainid`,`txid`,`from`,`nonce`,`rawtx`) VALUES (?,?,?,?,?);"
+	res, err := tx.ExecContext(ctx, insertBatchQuery, batch.ChainId, batch.Txid, batch.From, batch.Nonce, batch.Rawtx)
+	if err != nil {
+		return fmt.Errorf("NewDripBatch: save batch: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("NewDripBatch: get batch id: %w", err)
+	}
+	batch.Id = uint64(id)
+
+	const insertDripTxQuery = "INSERT INTO `drip_txs` (`batchid`,`pid`,`txid`,`rawtx`) VALUES (?,0,?,?);"
+	if _, err = tx.ExecContext(ctx, insertDripTxQuery, batch.Id, batch.Txid, batch.Rawtx); err != nil {
+		return fmt.Errorf("NewDripBatch: save drip tx: %w", err)
+	}
+
+	const updateDepositStatusQuery = "UPDATE `deposits` SET `status`=? WHERE id=?;"
+	for _, drip := range drips {
+		drip.BatchId = batch.Id
+		if err = insertDrip(ctx, tx, drip); err != nil {
+			return fmt.Errorf("NewDripBatch: %w", err)
+		}
+		if _, err = tx.ExecContext(ctx, updateDepositStatusQuery, DepositStatusProcessing, drip.Pid); err != nil {
+			return fmt.Errorf("NewDripBatch: update deposit tx status: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("NewDripBatch: commit: %w", err)
+	}
+	return nil
+}
+
+// GetPendingBatches returns the pending drip batches sent from the given account in nonce order
+func (m Metis) GetPendingBatches(ctx context.Context, chainId uint64, from string) ([]*PendingDrip, error) {
+	const query = "SELECT A.id as batchid,A.txid as txid,A.nonce as nonce,A.rawtx as rawtx,(SELECT UTC_TIMESTAMP()-INTERVAL TIMESTAMPDIFF(SECOND,MAX(C.ctime),NOW()) SECOND FROM `drip_txs` as C WHERE C.batchid=A.id) as stime " +
+		"FROM `drip_batches` as A WHERE A.`chainid`=? AND A.`from`=? AND A.`status`=0 ORDER BY A.`nonce` LIMIT 20;"
+	var res []*PendingDrip
+	if err := m.db.SelectContext(ctx, &res, query, chainId, from); err != nil {
+		return nil, fmt.Errorf("GetPendingBatches: %w", err)
+	}
+	return res, nil
+}
+
+// GetBatchTxids returns all the transaction hashes signed for the batch, the latest one is the first
+func (m Metis) GetBatchTxids(ctx context.Context, batchId uint64) ([]string, error) {
+	const query = "SELECT `txid` FROM `drip_txs` WHERE `batchid`=? ORDER BY `id` DESC;"
+	var res []string
+	if err := m.db.SelectContext(ctx, &res, query, batchId); err != nil {
+		return nil, fmt.Errorf("GetBatchTxids: %w", err)
+	}
+	return res, nil
+}
+
+// UpdateBatchTx replaces the batch transaction after it's re-signed, the replaced ones are kept as the candidates
+func (m Metis) UpdateBatchTx(ctx context.Context, batchId uint64, txid string, nonce uint64, rawtx []byte) (err error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("UpdateBatchTx: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("UpdateBatchTx: rollback: %s", rollbackError)
+		}
+	}()
+
+	const updateBatchQuery = "UPDATE `drip_batches` SET `txid`=?,`nonce`=?,`rawtx`=? WHERE `id`=?;"
+	res, err := tx.ExecContext(ctx, updateBatchQuery, txid, nonce, rawtx, batchId)
+	if err != nil {
+		return fmt.Errorf("UpdateBatchTx: update batch: %w", err)
+	}
+	if count, _ := res.RowsAffected(); count != 1 {
+		err = fmt.Errorf("UpdateBatchTx: affected row length should be 1")
+		return err
+	}
+
+	const updateDripQuery = "UPDATE `drips` SET `txid`=?,`nonce`=? WHERE `batchid`=?;"
+	if _, err = tx.ExecContext(ctx, updateDripQuery, txid, nonce, batchId); err != nil {
+		return fmt.Errorf("UpdateBatchTx: update drips: %w", err)
+	}
+
+	const insertDripTxQuery = "INSERT INTO `drip_txs` (`batchid`,`pid`,`txid`,`rawtx`) VALUES (?,0,?,?);"
+	if _, err = tx.ExecContext(ctx, insertDripTxQuery, batchId, txid, rawtx); err != nil {
+		return fmt.Errorf("UpdateBatchTx: save drip tx: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("UpdateBatchTx: commit: %w", err)
+	}
+	return nil
+}
+
+// GetBatchDrips returns the drips given by the batch
+func (m Metis) GetBatchDrips(ctx context.Context, batchId uint64) ([]*Drip, error) {
+	const query = "SELECT `pid`,`to`,`amount` FROM `drips` WHERE `batchid`=?;"
+	var res []*Drip
+	if err := m.db.SelectContext(ctx, &res, query, batchId); err != nil {
+		return nil, fmt.Errorf("GetBatchDrips: %w", err)
+	}
+	return res, nil
+}
+
+// SaveBatchReceipt records the receipt of the mined batch transaction,
+// the deposits of the received drips are done, the others are failed and waiting for a retry.
+func (m Metis) SaveBatchReceipt(ctx context.Context, batchId uint64, receipt *DripReceipt, received map[uint64]bool) (err error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("SaveBatchReceipt: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("SaveBatchReceipt: rollback: %s", rollbackError)
+		}
+	}()
+
+	const updateBatchQuery = "UPDATE `drip_batches` as A INNER JOIN `drip_txs` as B ON A.id=B.batchid " +
+		"SET A.`txid`=B.`txid`,A.`rawtx`=B.`rawtx`,A.`status`=1,A.`receiptstatus`=?,A.`blocknumber`=?,A.`blockhash`=?,A.`gasused`=?,A.`gasprice`=? WHERE B.`batchid`=? AND B.`txid`=?;"
+	args := []interface{}{receipt.Status, receipt.BlockNumber, receipt.Blockhash, receipt.GasUsed, receipt.EffectiveGasPrice, batchId, receipt.Txid}
+	res, err := tx.ExecContext(ctx, updateBatchQuery, args...)
+	if err != nil {
+		return fmt.Errorf("SaveBatchReceipt: update batch: %w", err)
+	}
+	if count, _ := res.RowsAffected(); count != 1 {
+		err = fmt.Errorf("SaveBatchReceipt: affected row length should be 1")
+		return err
+	}
+
+	var drips []*Drip
+	const selectDripQuery = "SELECT `pid` FROM `drips` WHERE `batchid`=?;"
+	if err = tx.SelectContext(ctx, &drips, selectDripQuery, batchId); err != nil {
+		return fmt.Errorf("SaveBatchReceipt: select drips: %w", err)
+	}
+
+	// the gas of the batch is shared by its drips
+	var gasUsed uint64
+	if len(drips) > 0 {
+		gasUsed = receipt.GasUsed / uint64(len(drips))
+	}
+	const updateDripQuery = "UPDATE `drips` SET `txid`=?,`receiptstatus`=?,`blocknumber`=?,`blockhash`=?,`gasused`=?,`gasprice`=? WHERE `pid`=?;"
+	const updateDepositQuery = "UPDATE `deposits` SET `status`=? WHERE `id`=?;"
+	for _, drip := range drips {
+		var receiptStatus, status = uint64(0), DepositStatusFailed
+		if receipt.Status == 1 && received[drip.Pid] {
+			receiptStatus, status = 1, DepositStatusDone
+		}
+		args := []interface{}{receipt.Txid, receiptStatus, receipt.BlockNumber, receipt.Blockhash, gasUsed, receipt.EffectiveGasPrice, drip.Pid}
+		if _, err = tx.ExecContext(ctx, updateDripQuery, args...); err != nil {
+			return fmt.Errorf("SaveBatchReceipt: update drip: %w", err)
+		}
+		if _, err = tx.ExecContext(ctx, updateDepositQuery, status, drip.Pid); err != nil {
+			return fmt.Errorf("SaveBatchReceipt: update deposit: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("SaveBatchReceipt: commit: %w", err)
+	}
+	return nil
+}
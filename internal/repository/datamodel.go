@@ -13,20 +13,71 @@ const (
 	DepositStatusProcessing
 	DepositStatusDone
 	DepositStatusIgnore
+	// the drip is reverted, it's waiting for a retry
+	DepositStatusFailed
+	// the deposit is rejected for a transient reason, it's decided again after the next retry time
+	DepositStatusDeferred
 )
 
 type Deposit struct {
 	Id        uint64        `db:"id"`
 	Txid      string        `db:"txid"`
+	LogIndex  uint          `db:"logindex"`
+	ChainId   uint64        `db:"chainid"`
 	Height    uint64        `db:"height"`
+	Blockhash string        `db:"blockhash"`
+	BlockTime time.Time     `db:"blocktime"`
 	L1Token   string        `db:"l1token"`
 	L2Token   string        `db:"l2token"`
 	From      string        `db:"from"`
 	To        string        `db:"to"`
 	Amount    bigint.Int    `db:"amount"`
 	Status    DepositStatus `db:"status"`
-	CreatedAt time.Time     `db:"ctime"`
-	UpdatedAt time.Time     `db:"mtime"`
+	// the sybil risk score from 0 to 100, it's nil before the deposit is scored
+	RiskScore *uint8 `db:"riskscore"`
+	// the layer1 account funding the sender first, it's nil before it's traced and empty if it's not found
+	Funder *string `db:"funder"`
+	// the times the deposit is deferred
+	Attempts  uint       `db:"attempts"`
+	NextRetry *time.Time `db:"nextretry"`
+	Reorged   bool       `db:"reorged"`
+	CreatedAt time.Time  `db:"ctime"`
+	UpdatedAt time.Time  `db:"mtime"`
+}
+
+type Withdrawal struct {
+	Id        uint64     `db:"id"`
+	Txid      string     `db:"txid"`
+	LogIndex  uint       `db:"logindex"`
+	ChainId   uint64     `db:"chainid"`
+	Height    uint64     `db:"height"`
+	Blockhash string     `db:"blockhash"`
+	BlockTime time.Time  `db:"blocktime"`
+	L1Token   string     `db:"l1token"`
+	L2Token   string     `db:"l2token"`
+	From      string     `db:"from"`
+	To        string     `db:"to"`
+	Amount    bigint.Int `db:"amount"`
+	CreatedAt time.Time  `db:"ctime"`
+}
+
+type Relay struct {
+	Id        uint64     `db:"id"`
+	ChainId   uint64     `db:"chainid"`
+	Txid      string     `db:"txid"`
+	LogIndex  uint       `db:"logindex"`
+	Height    uint64     `db:"height"`
+	BlockTime time.Time  `db:"blocktime"`
+	L1Token   string     `db:"l1token"`
+	L2Token   string     `db:"l2token"`
+	From      string     `db:"from"`
+	To        string     `db:"to"`
+	Amount    bigint.Int `db:"amount"`
+	Failed    bool       `db:"failed"`
+	Pid       uint64     `db:"pid"`
+	Latency   uint64     `db:"latency"`
+	CheckTime *time.Time `db:"checktime"`
+	CreatedAt time.Time  `db:"ctime"`
 }
 
 type Height struct {
@@ -36,10 +87,39 @@ type Height struct {
 
 type Drip struct {
 	Pid       uint64    `db:"pid"`
+	BatchId   uint64    `db:"batchid"`
 	Txid      string    `db:"txid"`
 	From      string    `db:"from"`
+	Nonce     uint64    `db:"nonce"`
 	To        string    `db:"to"`
 	Amount    float64   `db:"amount"`
+	Policy    string    `db:"policy"`
+	USD       float64   `db:"usd"`
+	Rawtx     []byte    `db:"rawtx"`
+	CreatedAt time.Time `db:"ctime"`
+	// the limits checked atomically when the drip is saved
+	Limits []DripLimit `db:"-"`
+	// the decision saved with the drip, it's not recorded if the drip is not saved
+	Decision *Decision `db:"-"`
+}
+
+// DripBatch is a disperse transaction giving the drips of many deposits
+type DripBatch struct {
+	Id        uint64    `db:"id"`
+	ChainId   uint64    `db:"chainid"`
+	Txid      string    `db:"txid"`
+	From      string    `db:"from"`
+	Nonce     uint64    `db:"nonce"`
 	Rawtx     []byte    `db:"rawtx"`
 	CreatedAt time.Time `db:"ctime"`
 }
+
+// DripReceipt is the on-chain outcome of the mined drip transaction
+type DripReceipt struct {
+	Txid              string     `db:"txid"`
+	Status            uint64     `db:"receiptstatus"`
+	BlockNumber       uint64     `db:"blocknumber"`
+	Blockhash         string     `db:"blockhash"`
+	GasUsed           uint64     `db:"gasused"`
+	EffectiveGasPrice bigint.Int `db:"gasprice"`
+}
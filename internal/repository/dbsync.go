@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 
 	"github.com/sirupsen/logrus"
 )
@@ -25,7 +26,40 @@ func (t Metis) InitHeight(ctx context.Context, defaultHeight uint64) (uint64, er
 	return hegiht + 1, nil
 }
 
-func (m Metis) SaveSyncedData(ctx context.Context, deposits []*Deposit, tail *Height) error {
+// GetRangeSpan returns the learned range sync span, 0 means it's not learned yet
+func (m Metis) GetRangeSpan(ctx context.Context) (uint64, error) {
+	const query = "SELECT `rangesync` FROM `height`;"
+	var span uint64
+	if err := m.db.QueryRowxContext(ctx, query).Scan(&span); err != nil {
+		return 0, fmt.Errorf("GetRangeSpan: %w", err)
+	}
+	return span, nil
+}
+
+func (m Metis) SaveRangeSpan(ctx context.Context, span uint64) error {
+	const query = "UPDATE `height` SET `rangesync`=?;"
+	if _, err := m.db.ExecContext(ctx, query, span); err != nil {
+		return fmt.Errorf("SaveRangeSpan: %w", err)
+	}
+	return nil
+}
+
+// FillDepositChainId sets the chain id of the legacy deposits synced before the chain id is recorded
+func (m Metis) FillDepositChainId(ctx context.Context, chainId uint64) error {
+	const query = "UPDATE `deposits` SET `chainid`=? WHERE `chainid`=0;"
+	res, err := m.db.ExecContext(ctx, query, chainId)
+	if err != nil {
+		return fmt.Errorf("FillDepositChainId: %w", err)
+	}
+	if count, _ := res.RowsAffected(); count > 0 {
+		logrus.Infof("FillDepositChainId: %d legacy deposits go to chain %d", count, chainId)
+	}
+	return nil
+}
+
+// SaveSyncedData saves the events of a synced range, the hash of every block having an event is saved
+// as a checkpoint along with the range tail, so a rollback after a reorg only touches the forked blocks.
+func (m Metis) SaveSyncedData(ctx context.Context, deposits []*Deposit, withdrawals []*Withdrawal, tail *Height) (err error) {
 	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("SaveSyncedData: begin tx %w", err)
@@ -40,17 +74,283 @@ func (m Metis) SaveSyncedData(ctx context.Context, deposits []*Deposit, tail *He
 		}
 	}()
 
-	const insertDepositQuery = "INSERT INTO `deposits` (`height`,`txid`,`l1token`,`l2token`,`from`,`to`,`amount`,`status`) VALUES (?,?,?,?,?,?,?,?);"
-	for _, item := range deposits {
-		args := []interface{}{item.Height, item.Txid, item.L1Token, item.L2Token, item.From, item.To, item.Amount, item.Status}
-		if _, err := tx.ExecContext(ctx, insertDepositQuery, args...); err != nil {
-			return fmt.Errorf("SaveSyncedData: insert deposit data: %w", err)
-		}
+	if _, err = upsertEvents(ctx, tx, deposits, withdrawals); err != nil {
+		return fmt.Errorf("SaveSyncedData: %w", err)
 	}
 
 	const updateHeightQuery = "UPDATE `height` SET `number`=?,`blockhash`=?;"
-	if _, err := tx.ExecContext(ctx, updateHeightQuery, tail.Number, tail.Blockhash); err != nil {
+	if _, err = tx.ExecContext(ctx, updateHeightQuery, tail.Number, tail.Blockhash); err != nil {
 		return fmt.Errorf("SaveSyncedData: update height data: %w", err)
 	}
+
+	const insertCheckpointQuery = "INSERT INTO `checkpoints` (`number`,`blockhash`) VALUES (?,?) ON DUPLICATE KEY UPDATE `blockhash`=VALUES(`blockhash`);"
+	for _, item := range eventCheckpoints(deposits, withdrawals, tail) {
+		if _, err = tx.ExecContext(ctx, insertCheckpointQuery, item.Number, item.Blockhash); err != nil {
+			return fmt.Errorf("SaveSyncedData: insert checkpoint: %w", err)
+		}
+	}
+
+	if tail.Number > CheckpointRetention {
+		const pruneCheckpointQuery = "DELETE FROM `checkpoints` WHERE `number`<?;"
+		if _, err = tx.ExecContext(ctx, pruneCheckpointQuery, tail.Number-CheckpointRetention); err != nil {
+			return fmt.Errorf("SaveSyncedData: prune checkpoints: %w", err)
+		}
+	}
 	return tx.Commit()
 }
+
+// eventCheckpoints returns the blocks having an event and the tail in ascending order
+func eventCheckpoints(deposits []*Deposit, withdrawals []*Withdrawal, tail *Height) []*Height {
+	blocks := map[uint64]string{tail.Number: tail.Blockhash}
+	for _, item := range deposits {
+		blocks[item.Height] = item.Blockhash
+	}
+	for _, item := range withdrawals {
+		blocks[item.Height] = item.Blockhash
+	}
+
+	checkpoints := make([]*Height, 0, len(blocks))
+	for number, blockhash := range blocks {
+		checkpoints = append(checkpoints, &Height{Number: number, Blockhash: blockhash})
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Number < checkpoints[j].Number
+	})
+	return checkpoints
+}
+
+// CheckpointRetention is the block distance from the synced tail within which
+// checkpoints are kept, it bounds the deepest reorg that can be rolled back.
+const CheckpointRetention = 10000
+
+// GetCheckpoints returns the synced checkpoints from the given height in descending order
+func (m Metis) GetCheckpoints(ctx context.Context, since uint64) ([]*Height, error) {
+	const query = "SELECT `number`,`blockhash` FROM `checkpoints` WHERE `number`>=? ORDER BY `number` DESC;"
+	var res []*Height
+	if err := m.db.SelectContext(ctx, &res, query, since); err != nil {
+		return nil, fmt.Errorf("GetCheckpoints: %w", err)
+	}
+	return res, nil
+}
+
+type RollbackResult struct {
+	Removed int64
+	Flagged []uint64
+}
+
+// Rollback rewinds the synced data to the fork point after a reorg.
+// Withdrawals and deposits without a drip above the fork point are removed and will be re-synced from the canonical chain,
+// the ones already have a drip are flagged as reorged for operator review, they're claimed back with their drips if re-mined.
+func (m Metis) Rollback(ctx context.Context, fork *Height) (res *RollbackResult, err error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Rollback: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("Rollback: rollback: %s", rollbackError)
+		}
+	}()
+
+	res = new(RollbackResult)
+	// the deposits not dripped yet are removed and get new ids after re-synced, their relays should be matched again
+	const unmatchRelayQuery = "UPDATE `relays` AS A INNER JOIN `deposits` AS B ON A.pid=B.id SET A.`pid`=0,A.`latency`=0 WHERE B.`height`>? AND B.`status` IN (?,?,?);"
+	if _, err = tx.ExecContext(ctx, unmatchRelayQuery, fork.Number, DepositStatusUnprocessed, DepositStatusIgnore, DepositStatusDeferred); err != nil {
+		return nil, fmt.Errorf("Rollback: unmatch relays: %w", err)
+	}
+
+	const deleteDepositQuery = "DELETE FROM `deposits` WHERE `height`>? AND `status` IN (?,?,?);"
+	result, err := tx.ExecContext(ctx, deleteDepositQuery, fork.Number, DepositStatusUnprocessed, DepositStatusIgnore, DepositStatusDeferred)
+	if err != nil {
+		return nil, fmt.Errorf("Rollback: delete deposits: %w", err)
+	}
+	res.Removed, _ = result.RowsAffected()
+
+	const selectDrippedQuery = "SELECT `id` FROM `deposits` WHERE `height`>? AND `reorged`=0;"
+	if err = tx.SelectContext(ctx, &res.Flagged, selectDrippedQuery, fork.Number); err != nil {
+		return nil, fmt.Errorf("Rollback: select dripped deposits: %w", err)
+	}
+
+	const flagDepositQuery = "UPDATE `deposits` SET `reorged`=1 WHERE `height`>?;"
+	if _, err = tx.ExecContext(ctx, flagDepositQuery, fork.Number); err != nil {
+		return nil, fmt.Errorf("Rollback: flag deposits: %w", err)
+	}
+
+	const deleteWithdrawalQuery = "DELETE FROM `withdrawals` WHERE `height`>?;"
+	if _, err = tx.ExecContext(ctx, deleteWithdrawalQuery, fork.Number); err != nil {
+		return nil, fmt.Errorf("Rollback: delete withdrawals: %w", err)
+	}
+
+	const deleteCheckpointQuery = "DELETE FROM `checkpoints` WHERE `number`>?;"
+	if _, err = tx.ExecContext(ctx, deleteCheckpointQuery, fork.Number); err != nil {
+		return nil, fmt.Errorf("Rollback: delete checkpoints: %w", err)
+	}
+
+	const updateHeightQuery = "UPDATE `height` SET `number`=?,`blockhash`=?;"
+	if _, err = tx.ExecContext(ctx, updateHeightQuery, fork.Number, fork.Blockhash); err != nil {
+		return nil, fmt.Errorf("Rollback: update height data: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("Rollback: commit: %w", err)
+	}
+	return res, nil
+}
+
+// UpsertResult counts the upserted rows by their previous state
+type UpsertResult struct {
+	New      int
+	Existing int
+	Changed  int
+}
+
+func (r *UpsertResult) count(res sql.Result) {
+	// the affected rows of an upsert is 1 for a new row, 2 for a changed row and 0 for an unchanged one
+	switch affected, _ := res.RowsAffected(); affected {
+	case 1:
+		r.New++
+	case 2:
+		r.Changed++
+	default:
+		r.Existing++
+	}
+}
+
+type UpsertedEvents struct {
+	Deposits    UpsertResult
+	Withdrawals UpsertResult
+}
+
+func (e *UpsertedEvents) Add(other *UpsertedEvents) {
+	e.Deposits.New += other.Deposits.New
+	e.Deposits.Existing += other.Deposits.Existing
+	e.Deposits.Changed += other.Deposits.Changed
+	e.Withdrawals.New += other.Withdrawals.New
+	e.Withdrawals.Existing += other.Withdrawals.Existing
+	e.Withdrawals.Changed += other.Withdrawals.Changed
+}
+
+// the legacy deposits saved before the log index is known have the made-up log indexes from it
+const legacyLogIndex = 1 << 31
+
+// claimDeposit gives the log index to the legacy or reorged row of the same event, it returns false if there is none.
+// The legacy rows are matched by the txid and the decoded fields since their log indexes are made up,
+// and so are the reorged rows as the transaction can be re-mined at another log index, they keep their drips then.
+func claimDeposit(ctx context.Context, tx *sql.Tx, item *Deposit) (bool, error) {
+	var exists int
+	const selectQuery = "SELECT COUNT(*) FROM `deposits` WHERE `txid`=? AND `logindex`=?;"
+	if err := tx.QueryRowContext(ctx, selectQuery, item.Txid, item.LogIndex).Scan(&exists); err != nil {
+		return false, fmt.Errorf("select deposit: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	const claimQuery = "UPDATE `deposits` SET `logindex`=?,`height`=?,`blockhash`=?,`blocktime`=?,`chainid`=?,`reorged`=0 " +
+		"WHERE `txid`=? AND (`logindex`>=? OR `reorged`=1) AND `l1token`=? AND `l2token`=? AND `from`=? AND `to`=? AND `amount`=? ORDER BY `id` LIMIT 1;"
+	args := []interface{}{item.LogIndex, item.Height, item.Blockhash, item.BlockTime, item.ChainId,
+		item.Txid, legacyLogIndex, item.L1Token, item.L2Token, item.From, item.To, item.Amount}
+	result, err := tx.ExecContext(ctx, claimQuery, args...)
+	if err != nil {
+		return false, fmt.Errorf("claim deposit: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim deposit: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// refreshDeposit updates the decoded fields of an existing deposit which is not dripped yet,
+// the dripped ones keep the fields they were paid by.
+func refreshDeposit(ctx context.Context, tx *sql.Tx, item *Deposit) (bool, error) {
+	const query = "UPDATE `deposits` SET `chainid`=?,`l1token`=?,`l2token`=?,`from`=?,`to`=?,`amount`=? " +
+		"WHERE `txid`=? AND `logindex`=? AND `status` IN (?,?,?);"
+	args := []interface{}{item.ChainId, item.L1Token, item.L2Token, item.From, item.To, item.Amount,
+		item.Txid, item.LogIndex, DepositStatusUnprocessed, DepositStatusIgnore, DepositStatusDeferred}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("refresh deposit data: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("refresh deposit data: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// upsertEvents saves the events idempotently, an event is identified by (txid, logindex),
+// re-syncing a range refreshes its block info, and the decoded fields of the deposits not dripped yet.
+func upsertEvents(ctx context.Context, tx *sql.Tx, deposits []*Deposit, withdrawals []*Withdrawal) (*UpsertedEvents, error) {
+	var res = new(UpsertedEvents)
+
+	const insertDepositQuery = "INSERT INTO `deposits` (`height`,`blockhash`,`blocktime`,`txid`,`logindex`,`chainid`,`l1token`,`l2token`,`from`,`to`,`amount`,`status`) VALUES (?,?,?,?,?,?,?,?,?,?,?,?) " +
+		"ON DUPLICATE KEY UPDATE `height`=VALUES(`height`),`blockhash`=VALUES(`blockhash`),`blocktime`=VALUES(`blocktime`),`reorged`=0;"
+	for _, item := range deposits {
+		claimed, err := claimDeposit(ctx, tx, item)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			res.Deposits.Changed++
+			continue
+		}
+		args := []interface{}{item.Height, item.Blockhash, item.BlockTime, item.Txid, item.LogIndex, item.ChainId, item.L1Token, item.L2Token, item.From, item.To, item.Amount, item.Status}
+		result, err := tx.ExecContext(ctx, insertDepositQuery, args...)
+		if err != nil {
+			return nil, fmt.Errorf("insert deposit data: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected != 1 {
+			refreshed, err := refreshDeposit(ctx, tx, item)
+			if err != nil {
+				return nil, err
+			}
+			if refreshed {
+				res.Deposits.Changed++
+				continue
+			}
+		}
+		res.Deposits.count(result)
+	}
+
+	const insertWithdrawalQuery = "INSERT INTO `withdrawals` (`height`,`blockhash`,`blocktime`,`txid`,`logindex`,`chainid`,`l1token`,`l2token`,`from`,`to`,`amount`) VALUES (?,?,?,?,?,?,?,?,?,?,?) " +
+		"ON DUPLICATE KEY UPDATE `height`=VALUES(`height`),`blockhash`=VALUES(`blockhash`),`blocktime`=VALUES(`blocktime`),`chainid`=VALUES(`chainid`);"
+	for _, item := range withdrawals {
+		args := []interface{}{item.Height, item.Blockhash, item.BlockTime, item.Txid, item.LogIndex, item.ChainId, item.L1Token, item.L2Token, item.From, item.To, item.Amount}
+		result, err := tx.ExecContext(ctx, insertWithdrawalQuery, args...)
+		if err != nil {
+			return nil, fmt.Errorf("insert withdrawal data: %w", err)
+		}
+		res.Withdrawals.count(result)
+	}
+	return res, nil
+}
+
+// SaveBackfilledData saves the events in a backfilled range, the height cursor and checkpoints are untouched
+func (m Metis) SaveBackfilledData(ctx context.Context, deposits []*Deposit, withdrawals []*Withdrawal) (res *UpsertedEvents, err error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("SaveBackfilledData: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("SaveBackfilledData: rollback: %s", rollbackError)
+		}
+	}()
+
+	if res, err = upsertEvents(ctx, tx, deposits, withdrawals); err != nil {
+		return nil, fmt.Errorf("SaveBackfilledData: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("SaveBackfilledData: commit: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type DripDecision uint8
+
+const (
+	// the deposit gets a drip
+	DecisionDrip DripDecision = iota
+	// the deposit is ignored
+	DecisionIgnore
+	// the deposit is skipped and stays unprocessed, e.g. for an exhausted budget
+	DecisionSkip
+	// the deposit is rejected for a transient reason, and is decided again later
+	DecisionDefer
+)
+
+func (d DripDecision) String() string {
+	switch d {
+	case DecisionDrip:
+		return "drip"
+	case DecisionIgnore:
+		return "ignore"
+	case DecisionSkip:
+		return "skip"
+	case DecisionDefer:
+		return "defer"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(d))
+	}
+}
+
+func (d DripDecision) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Decision records how the faucet decides the drip of a deposit, a deposit may have many decisions if it's skipped or retried
+type Decision struct {
+	Id       uint64       `db:"id" json:"id"`
+	Pid      uint64       `db:"pid" json:"pid"`
+	ChainId  uint64       `db:"chainid" json:"chainid"`
+	Txid     string       `db:"txid" json:"txid"`
+	From     string       `db:"from" json:"from"`
+	To       string       `db:"to" json:"to"`
+	Policy   string       `db:"policy" json:"policy"`
+	Decision DripDecision `db:"decision" json:"decision"`
+	// the reason code
+	Reason  string `db:"reason" json:"reason"`
+	Message string `db:"message" json:"message"`
+	// the json array of the check outcomes
+	Checks     []byte    `db:"checks" json:"-"`
+	TokenPrice float64   `db:"tokenprice" json:"tokenprice"`
+	DepositUSD float64   `db:"depositusd" json:"depositusd"`
+	DripAmount float64   `db:"dripamount" json:"dripamount"`
+	DripUSD    float64   `db:"dripusd" json:"dripusd"`
+	CreatedAt  time.Time `db:"ctime" json:"ctime"`
+}
+
+func (m Metis) SaveDecision(ctx context.Context, decision *Decision) error {
+	if err := insertDecision(ctx, m.db, decision); err != nil {
+		return fmt.Errorf("SaveDecision: %w", err)
+	}
+	return nil
+}
+
+// SaveDecisionIfChanged saves the decision unless the last decision of the deposit has the same outcome, reason and policy,
+// it keeps a deposit skipped in every loop from flooding the table.
+func (m Metis) SaveDecisionIfChanged(ctx context.Context, decision *Decision) (saved bool, err error) {
+	const query = "SELECT COUNT(*) FROM (SELECT `decision`,`reason`,`policy` FROM `decisions` WHERE `pid`=? ORDER BY `id` DESC LIMIT 1) AS A " +
+		"WHERE A.`decision`=? AND A.`reason`=? AND A.`policy`=?;"
+	var count int
+	if err := m.db.QueryRowContext(ctx, query, decision.Pid, decision.Decision, decision.Reason, decision.Policy).Scan(&count); err != nil {
+		return false, fmt.Errorf("SaveDecisionIfChanged: %w", err)
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := insertDecision(ctx, m.db, decision); err != nil {
+		return false, fmt.Errorf("SaveDecisionIfChanged: %w", err)
+	}
+	return true, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertDecision(ctx context.Context, db execer, decision *Decision) error {
+	const query = "INSERT INTO `decisions` (`pid`,`chainid`,`txid`,`from`,`to`,`policy`,`decision`,`reason`,`message`,`checks`,`tokenprice`,`depositusd`,`dripamount`,`dripusd`) " +
+		"VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?);"
+	args := []interface{}{decision.Pid, decision.ChainId, decision.Txid, decision.From, decision.To, decision.Policy, decision.Decision, decision.Reason,
+		decision.Message, decision.Checks, decision.TokenPrice, decision.DepositUSD, decision.DripAmount, decision.DripUSD}
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("save decision: %w", err)
+	}
+	return nil
+}
+
+// GetDecisions returns the decisions of the deposit txid, or of the deposits from or to the address, the latest first
+func (m Metis) GetDecisions(ctx context.Context, txid, address string, limit int) ([]*Decision, error) {
+	const query = "SELECT * FROM `decisions` WHERE `txid`=? OR `from`=? OR `to`=? ORDER BY `id` DESC LIMIT ?;"
+	var res []*Decision
+	if err := m.db.SelectContext(ctx, &res, query, txid, address, address, limit); err != nil {
+		return nil, fmt.Errorf("GetDecisions: %w", err)
+	}
+	return res, nil
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -12,35 +14,57 @@ type DepositTxStream struct {
 	Error error
 }
 
-func (m Metis) GetDepositTxStream(ctx context.Context, status DepositStatus) <-chan DepositTxStream {
+// GetDepositTxStream returns the deposits which have been relayed to layer2 successfully in the order of id,
+// the deferred deposits are returned as well once their retry time is due
+func (m Metis) GetDepositTxStream(ctx context.Context, chainId uint64, status DepositStatus) <-chan DepositTxStream {
+	// the reorged deposits are never dripped, they're either re-synced or gone
+	const query = "SELECT A.* FROM `deposits` AS A INNER JOIN `relays` AS B ON B.pid=A.id WHERE A.`chainid`=? " +
+		"AND (A.`status`=? OR (A.`status`=? AND A.`nextretry`<=?)) AND A.`reorged`=0 AND B.`failed`=0 AND A.`id`>? ORDER BY A.`id` LIMIT ?;"
+	return m.depositStream(ctx, query, chainId, status, DepositStatusDeferred, time.Now())
+}
+
+// DeferDeposit defers the deposit until the next retry time
+func (m Metis) DeferDeposit(ctx context.Context, id uint64, nextRetry time.Time) error {
+	const query = "UPDATE `deposits` SET `status`=?, `attempts`=`attempts`+1, `nextretry`=? WHERE `id`=?;"
+	if _, err := m.db.ExecContext(ctx, query, DepositStatusDeferred, nextRetry, id); err != nil {
+		return fmt.Errorf("DeferDeposit: %w", err)
+	}
+	return nil
+}
+
+// the deposits are streamed page by page
+const depositPageSize = 100
+
+// depositStream streams all the deposits matched by the query in the order of id,
+// the query must end with "A.`id`>? ORDER BY A.`id` LIMIT ?" to page by id,
+// so the deposits kept undecided at the head don't starve the ones behind them.
+func (m Metis) depositStream(ctx context.Context, query string, args ...interface{}) <-chan DepositTxStream {
 	var stream = make(chan DepositTxStream, 5)
-	const query = "SELECT * FROM `deposits` WHERE `status`=? LIMIT 100;"
 
 	go func() {
 		defer close(stream)
 
-		rows, err := m.db.QueryxContext(ctx, query, status)
-		if err != nil {
-			select {
-			case <-ctx.Done():
-			case stream <- DepositTxStream{Error: err}:
-			}
-			return
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var tx Deposit
-			if err := rows.StructScan(&tx); err != nil {
+		var lastId uint64
+		for {
+			var page []*Deposit
+			if err := m.db.SelectContext(ctx, &page, query, append(args, lastId, depositPageSize)...); err != nil {
 				select {
 				case <-ctx.Done():
 				case stream <- DepositTxStream{Error: err}:
 				}
 				return
 			}
-			select {
-			case <-ctx.Done():
-			case stream <- DepositTxStream{Data: &tx}:
+
+			for _, item := range page {
+				select {
+				case <-ctx.Done():
+					return
+				case stream <- DepositTxStream{Data: item}:
+				}
+				lastId = item.Id
+			}
+			if len(page) < depositPageSize {
+				return
 			}
 		}
 	}()
@@ -48,17 +72,46 @@ func (m Metis) GetDepositTxStream(ctx context.Context, status DepositStatus) <-c
 	return stream
 }
 
-func (m Metis) HasGotDrip(ctx context.Context, address string) (bool, error) {
-	const query = "SELECT COUNT(*) FROM `drips` WHERE `to`=?;"
+// HasGotDrip returns true if the address has never got a drip except the one of the given deposit
+func (m Metis) HasGotDrip(ctx context.Context, chainId uint64, address string, exceptPid uint64) (bool, error) {
+	// the reverted drips don't count
+	const query = "SELECT COUNT(*) FROM `drips` as A INNER JOIN `deposits` as B ON A.pid=B.id WHERE A.`to`=? AND B.`chainid`=? AND A.`pid`<>? AND (A.`receiptstatus` IS NULL OR A.`receiptstatus`<>0);"
 	var count int
-	if err := m.db.QueryRowContext(ctx, query, address).Scan(&count); err != nil {
+	if err := m.db.QueryRowContext(ctx, query, address, chainId, exceptPid).Scan(&count); err != nil {
 		return false, fmt.Errorf("HasGotDrip: %w", err)
 	}
 	return count == 0, nil
 }
 
-func (m Metis) NewDrip(ctx context.Context, deposit *Deposit, drip *Drip) error {
-	tx, err := m.db.BeginTx(ctx, nil)
+// GetDripSpent sums the Metis and USD values of the drips given since the time, the drips of all the policies are summed if the policy is empty
+func (m Metis) GetDripSpent(ctx context.Context, chainId uint64, policy string, since time.Time) (metis, usd float64, err error) {
+	// the reverted drips don't count
+	query := "SELECT COALESCE(SUM(A.`amount`),0), COALESCE(SUM(A.`usd`),0) FROM `drips` as A INNER JOIN `deposits` as B ON A.pid=B.id " +
+		"WHERE B.`chainid`=? AND (A.`receiptstatus` IS NULL OR A.`receiptstatus`<>0)"
+	args := []interface{}{chainId}
+	if !since.IsZero() {
+		query += " AND A.`ctime`>=NOW()-INTERVAL ? MICROSECOND"
+		args = append(args, elapsedSince(since))
+	}
+	if policy != "" {
+		query += " AND A.`policy`=?"
+		args = append(args, policy)
+	}
+	if err := m.db.QueryRowContext(ctx, query+";", args...).Scan(&metis, &usd); err != nil {
+		return 0, 0, fmt.Errorf("GetDripSpent: %w", err)
+	}
+	return metis, usd, nil
+}
+
+// elapsedSince returns the microseconds passed since the time, the ctime is saved on the database clock and time zone,
+// so a window is compared against it relative to NOW() like the drip limits.
+func elapsedSince(since time.Time) int64 {
+	return max(time.Since(since).Microseconds(), 0)
+}
+
+func (m Metis) NewDrip(ctx context.Context, deposit *Deposit, drip *Drip) (err error) {
+	// the drip limits are counted after the locks are taken, so every statement reads the latest committed drips
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
 	if err != nil {
 		return fmt.Errorf("SaveSyncingData: begin tx %w", err)
 	}
@@ -74,29 +127,63 @@ func (m Metis) NewDrip(ctx context.Context, deposit *Deposit, drip *Drip) error
 
 	var status = DepositStatusIgnore
 	if drip != nil {
-		const insertDripQuery = "INSERT INTO `drips` (`pid`,`txid`,`from`,`to`,`amount`,`rawtx`) VALUES (?,?,?,?,?,?);"
 		if drip.Pid != deposit.Id {
-			return fmt.Errorf("NewDrip: drip id is not same with deposit id")
+			err = fmt.Errorf("NewDrip: drip id is not same with deposit id")
+			return err
 		}
-		args := []interface{}{drip.Pid, drip.Txid, drip.From, drip.To, drip.Amount, drip.Rawtx}
-		if _, err := tx.ExecContext(ctx, insertDripQuery, args...); err != nil {
-			return fmt.Errorf("NewDrip: save drip: %w", err)
+		if err = lockDripLimits(ctx, tx, []*Drip{drip}); err != nil {
+			return fmt.Errorf("NewDrip: %w", err)
+		}
+		if err = insertDrip(ctx, tx, drip); err != nil {
+			return fmt.Errorf("NewDrip: %w", err)
+		}
+		const insertDripTxQuery = "INSERT INTO `drip_txs` (`pid`,`txid`,`rawtx`) VALUES (?,?,?);"
+		if _, err = tx.ExecContext(ctx, insertDripTxQuery, drip.Pid, drip.Txid, drip.Rawtx); err != nil {
+			return fmt.Errorf("NewDrip: save drip tx: %w", err)
 		}
 		status = DepositStatusProcessing
 	}
 
 	const updateDepositStatusQuery = "UPDATE `deposits` SET `status`=? WHERE id=?;"
-	if _, err := tx.ExecContext(ctx, updateDepositStatusQuery, status, deposit.Id); err != nil {
+	if _, err = tx.ExecContext(ctx, updateDepositStatusQuery, status, deposit.Id); err != nil {
 		return fmt.Errorf("NewDrip: update deposit tx status: %w", err)
 	}
 
 	return tx.Commit()
 }
 
+// insertDrip saves the drip and its decision, the one of a failed deposit is overwritten when it's retried
+func insertDrip(ctx context.Context, tx *sql.Tx, drip *Drip) error {
+	if err := checkDripLimits(ctx, tx, drip); err != nil {
+		return err
+	}
+	const insertDripQuery = "INSERT INTO `drips` (`pid`,`batchid`,`txid`,`from`,`nonce`,`to`,`amount`,`policy`,`usd`,`rawtx`) VALUES (?,?,?,?,?,?,?,?,?,?) " +
+		"ON DUPLICATE KEY UPDATE `batchid`=VALUES(`batchid`),`txid`=VALUES(`txid`),`from`=VALUES(`from`),`nonce`=VALUES(`nonce`),`to`=VALUES(`to`),`amount`=VALUES(`amount`),`policy`=VALUES(`policy`),`usd`=VALUES(`usd`),`rawtx`=VALUES(`rawtx`)," +
+		"`receiptstatus`=NULL,`blocknumber`=NULL,`blockhash`=NULL,`gasused`=NULL,`gasprice`=NULL,`ctime`=CURRENT_TIMESTAMP;"
+	args := []interface{}{drip.Pid, drip.BatchId, drip.Txid, drip.From, drip.Nonce, drip.To, drip.Amount, drip.Policy, drip.USD, drip.Rawtx}
+	if _, err := tx.ExecContext(ctx, insertDripQuery, args...); err != nil {
+		return fmt.Errorf("save drip: %w", err)
+	}
+	// the transactions of the previous failed drip are not the candidates any more
+	const retireDripTxQuery = "UPDATE `drip_txs` SET `retired`=1 WHERE `pid`=?;"
+	if _, err := tx.ExecContext(ctx, retireDripTxQuery, drip.Pid); err != nil {
+		return fmt.Errorf("retire drip txs: %w", err)
+	}
+	if drip.Decision != nil {
+		return insertDecision(ctx, tx, drip.Decision)
+	}
+	return nil
+}
+
 type PendingDrip struct {
-	Id    uint64 `db:"id"`
-	Txid  string `db:"txid"`
-	Rawtx []byte `db:"rawtx"`
+	Id uint64 `db:"id"`
+	// the drip batch id, the id is 0 if it's a batch
+	BatchId uint64 `db:"batchid"`
+	Txid    string `db:"txid"`
+	Nonce   uint64 `db:"nonce"`
+	Rawtx   []byte `db:"rawtx"`
+	// the time the current transaction is signed in UTC, the ctime is in the database time zone
+	SentAt time.Time `db:"stime"`
 }
 
 type PendingDripStream struct {
@@ -104,14 +191,16 @@ type PendingDripStream struct {
 	Error error
 }
 
-func (m Metis) GetPendingDripsStream(ctx context.Context) <-chan PendingDripStream {
+// GetPendingDripsStream returns the pending drips sent from the given account in nonce order
+func (m Metis) GetPendingDripsStream(ctx context.Context, chainId uint64, from string) <-chan PendingDripStream {
 	var stream = make(chan PendingDripStream, 5)
-	const query = "SELECT A.id as id,B.txid as txid,B.rawtx as rawtx  FROM `deposits` as A INNER JOIN `drips` as B ON A.id=B.pid WHERE `status`=? LIMIT 20;"
+	const query = "SELECT A.id as id,B.txid as txid,B.nonce as nonce,B.rawtx as rawtx,(SELECT UTC_TIMESTAMP()-INTERVAL TIMESTAMPDIFF(SECOND,MAX(C.ctime),NOW()) SECOND FROM `drip_txs` as C WHERE C.pid=B.pid AND C.retired=0) as stime " +
+		"FROM `deposits` as A INNER JOIN `drips` as B ON A.id=B.pid WHERE A.`chainid`=? AND A.`status`=? AND B.`from`=? AND B.`batchid`=0 ORDER BY B.`nonce` LIMIT 20;"
 
 	go func() {
 		defer close(stream)
 
-		rows, err := m.db.QueryxContext(ctx, query, DepositStatusProcessing)
+		rows, err := m.db.QueryxContext(ctx, query, chainId, DepositStatusProcessing, from)
 		if err != nil {
 			select {
 			case <-ctx.Done():
@@ -151,3 +240,153 @@ func (m Metis) UpdateDripStatus(ctx context.Context, id uint64, status DepositSt
 	}
 	return nil
 }
+
+// GetMaxDripNonce returns the max nonce assigned to the drips sent from the given account, ok is false if there is no drip yet
+func (m Metis) GetMaxDripNonce(ctx context.Context, chainId uint64, from string) (nonce uint64, ok bool, err error) {
+	const query = "SELECT MAX(B.nonce) FROM `deposits` as A INNER JOIN `drips` as B ON A.id=B.pid WHERE A.`chainid`=? AND B.`from`=?;"
+	var res sql.NullInt64
+	if err := m.db.QueryRowContext(ctx, query, chainId, from).Scan(&res); err != nil {
+		return 0, false, fmt.Errorf("GetMaxDripNonce: %w", err)
+	}
+	return uint64(res.Int64), res.Valid, nil
+}
+
+// GetPendingDripNonces returns the nonces of all the pending drips sent from the given account
+func (m Metis) GetPendingDripNonces(ctx context.Context, chainId uint64, from string) ([]uint64, error) {
+	const query = "SELECT B.nonce FROM `deposits` as A INNER JOIN `drips` as B ON A.id=B.pid WHERE A.`chainid`=? AND A.`status`=? AND B.`from`=?;"
+	var res []uint64
+	if err := m.db.SelectContext(ctx, &res, query, chainId, DepositStatusProcessing, from); err != nil {
+		return nil, fmt.Errorf("GetPendingDripNonces: %w", err)
+	}
+	return res, nil
+}
+
+// UpdateDripTx replaces the drip transaction after it's re-signed, the replaced ones are kept as the candidates
+func (m Metis) UpdateDripTx(ctx context.Context, pid uint64, txid string, nonce uint64, rawtx []byte) (err error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("UpdateDripTx: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("UpdateDripTx: rollback: %s", rollbackError)
+		}
+	}()
+
+	const updateDripQuery = "UPDATE `drips` SET `txid`=?,`nonce`=?,`rawtx`=? WHERE `pid`=?;"
+	res, err := tx.ExecContext(ctx, updateDripQuery, txid, nonce, rawtx, pid)
+	if err != nil {
+		return fmt.Errorf("UpdateDripTx: update drip: %w", err)
+	}
+	if count, _ := res.RowsAffected(); count != 1 {
+		err = fmt.Errorf("UpdateDripTx: affected row length should be 1")
+		return err
+	}
+
+	const insertDripTxQuery = "INSERT INTO `drip_txs` (`pid`,`txid`,`rawtx`) VALUES (?,?,?);"
+	if _, err = tx.ExecContext(ctx, insertDripTxQuery, pid, txid, rawtx); err != nil {
+		return fmt.Errorf("UpdateDripTx: save drip tx: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("UpdateDripTx: commit: %w", err)
+	}
+	return nil
+}
+
+// GetDripTxids returns all the transaction hashes signed for the drip, the latest one is the first
+func (m Metis) GetDripTxids(ctx context.Context, pid uint64) ([]string, error) {
+	const query = "SELECT `txid` FROM `drip_txs` WHERE `pid`=? AND `retired`=0 ORDER BY `id` DESC;"
+	var res []string
+	if err := m.db.SelectContext(ctx, &res, query, pid); err != nil {
+		return nil, fmt.Errorf("GetDripTxids: %w", err)
+	}
+	return res, nil
+}
+
+// SaveDripReceipt records the receipt of the mined one of the drip transactions,
+// the deposit is done if the drip is successful, otherwise it's failed and waiting for a retry.
+func (m Metis) SaveDripReceipt(ctx context.Context, pid uint64, receipt *DripReceipt) (err error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("SaveDripReceipt: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("SaveDripReceipt: rollback: %s", rollbackError)
+		}
+	}()
+
+	const updateDripQuery = "UPDATE `drips` as A INNER JOIN `drip_txs` as B ON A.pid=B.pid " +
+		"SET A.`txid`=B.`txid`,A.`rawtx`=B.`rawtx`,A.`receiptstatus`=?,A.`blocknumber`=?,A.`blockhash`=?,A.`gasused`=?,A.`gasprice`=? WHERE B.`pid`=? AND B.`txid`=?;"
+	args := []interface{}{receipt.Status, receipt.BlockNumber, receipt.Blockhash, receipt.GasUsed, receipt.EffectiveGasPrice, pid, receipt.Txid}
+	res, err := tx.ExecContext(ctx, updateDripQuery, args...)
+	if err != nil {
+		return fmt.Errorf("SaveDripReceipt: update drip: %w", err)
+	}
+	if count, _ := res.RowsAffected(); count != 1 {
+		err = fmt.Errorf("SaveDripReceipt: affected row length should be 1")
+		return err
+	}
+
+	var status = DepositStatusDone
+	if receipt.Status == 0 {
+		status = DepositStatusFailed
+	}
+	const updateDepositQuery = "UPDATE `deposits` SET `status`=? WHERE `id`=?;"
+	if _, err = tx.ExecContext(ctx, updateDepositQuery, status, pid); err != nil {
+		return fmt.Errorf("SaveDripReceipt: update deposit: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("SaveDripReceipt: commit: %w", err)
+	}
+	return nil
+}
+
+// GetFailedDepositCount returns the count of the deposits whose drips are reverted
+func (m Metis) GetFailedDepositCount(ctx context.Context, chainId uint64) (int, error) {
+	const query = "SELECT COUNT(*) FROM `deposits` WHERE `chainid`=? AND `status`=?;"
+	var count int
+	if err := m.db.QueryRowContext(ctx, query, chainId, DepositStatusFailed).Scan(&count); err != nil {
+		return 0, fmt.Errorf("GetFailedDepositCount: %w", err)
+	}
+	return count, nil
+}
+
+// RetryFailedDeposits makes the failed deposits unprocessed again, all of them are retried if the id is 0
+func (m Metis) RetryFailedDeposits(ctx context.Context, id uint64) (int64, error) {
+	const query = "UPDATE `deposits` SET `status`=? WHERE `status`=? AND (?=0 OR `id`=?);"
+	res, err := m.db.ExecContext(ctx, query, DepositStatusUnprocessed, DepositStatusFailed, id, id)
+	if err != nil {
+		return 0, fmt.Errorf("RetryFailedDeposits: %w", err)
+	}
+	count, _ := res.RowsAffected()
+	return count, nil
+}
+
+// GetDripsWithoutNonce returns the legacy drips saved before the nonce is recorded
+func (m Metis) GetDripsWithoutNonce(ctx context.Context) ([]*PendingDrip, error) {
+	const query = "SELECT `pid` as id,`txid`,0 as nonce,`rawtx` FROM `drips` WHERE `nonce` IS NULL;"
+	var res []*PendingDrip
+	if err := m.db.SelectContext(ctx, &res, query); err != nil {
+		return nil, fmt.Errorf("GetDripsWithoutNonce: %w", err)
+	}
+	return res, nil
+}
+
+func (m Metis) SetDripNonce(ctx context.Context, pid uint64, nonce uint64) error {
+	const query = "UPDATE `drips` SET `nonce`=? WHERE `pid`=?;"
+	if _, err := m.db.ExecContext(ctx, query, nonce, pid); err != nil {
+		return fmt.Errorf("SetDripNonce: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type DripLimitKind uint8
+
+const (
+	// the drips received by a layer2 address
+	DripLimitRecipient DripLimitKind = iota
+	// the drips given for the deposits of a layer1 address
+	DripLimitSender
+)
+
+func (k DripLimitKind) String() string {
+	if k == DripLimitSender {
+		return "sender"
+	}
+	return "recipient"
+}
+
+// DripLimit bounds the drips of an address in a rolling window
+type DripLimit struct {
+	ChainId  uint64
+	Kind     DripLimitKind
+	Address  string
+	MaxDrips int
+	Window   time.Duration
+}
+
+// ErrDripLimited means saving the drip exceeds a limit, nothing is saved
+type ErrDripLimited struct {
+	Pid   uint64
+	Limit DripLimit
+	Count int
+}
+
+func (e ErrDripLimited) Error() string {
+	return fmt.Sprintf("%s %s has got %d drips in %s, limit %d", e.Limit.Kind, e.Limit.Address, e.Count, e.Limit.Window, e.Limit.MaxDrips)
+}
+
+// CountDrips returns the drips of the address in the rolling window, the reverted drips don't count
+func (m Metis) CountDrips(ctx context.Context, limit DripLimit) (int, error) {
+	return countDrips(ctx, m.db, limit, 0)
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func countDrips(ctx context.Context, db queryRower, limit DripLimit, exceptPid uint64) (int, error) {
+	column := "A.`to`"
+	if limit.Kind == DripLimitSender {
+		column = "B.`from`"
+	}
+	// the database clock is used as the drip ctime, so the faucets with clock skews share the same windows
+	query := "SELECT COUNT(*) FROM `drips` as A INNER JOIN `deposits` as B ON A.pid=B.id WHERE B.`chainid`=? AND " + column + "=? " +
+		"AND A.`pid`<>? AND A.`ctime`>=NOW()-INTERVAL ? SECOND AND (A.`receiptstatus` IS NULL OR A.`receiptstatus`<>0);"
+	var count int
+	if err := db.QueryRowContext(ctx, query, limit.ChainId, limit.Address, exceptPid, int64(limit.Window.Seconds())).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count drips: %w", err)
+	}
+	return count, nil
+}
+
+// lockDripLimits locks the limited addresses of the drips until the transaction ends, the concurrent faucets are serialized on the lock rows.
+// All the rows are locked in order before any drip is counted, so the faucets don't deadlock on them.
+func lockDripLimits(ctx context.Context, tx *sql.Tx, drips []*Drip) error {
+	type lockKey struct {
+		kind    DripLimitKind
+		address string
+	}
+	var keys []lockKey
+	seen := make(map[lockKey]bool)
+	for _, drip := range drips {
+		for _, limit := range drip.Limits {
+			key := lockKey{kind: limit.Kind, address: limit.Address}
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].address < keys[j].address
+	})
+
+	const insertLockQuery = "INSERT IGNORE INTO `drip_limit_locks` (`kind`,`address`) VALUES (?,?);"
+	const lockQuery = "SELECT `kind` FROM `drip_limit_locks` WHERE `kind`=? AND `address`=? FOR UPDATE;"
+	for _, key := range keys {
+		if _, err := tx.ExecContext(ctx, insertLockQuery, key.kind, key.address); err != nil {
+			return fmt.Errorf("insert drip limit lock: %w", err)
+		}
+		var kind DripLimitKind
+		if err := tx.QueryRowContext(ctx, lockQuery, key.kind, key.address).Scan(&kind); err != nil {
+			return fmt.Errorf("lock drip limit: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkDripLimits checks the drip against the limits, the addresses should be locked by lockDripLimits.
+// The transaction should be READ COMMITTED, so the counts see the drips committed by the other faucets while waiting for the locks.
+func checkDripLimits(ctx context.Context, tx *sql.Tx, drip *Drip) error {
+	for _, limit := range drip.Limits {
+		count, err := countDrips(ctx, tx, limit, drip.Pid)
+		if err != nil {
+			return err
+		}
+		if count >= limit.MaxDrips {
+			return ErrDripLimited{Pid: drip.Pid, Limit: limit, Count: count}
+		}
+	}
+	return nil
+}
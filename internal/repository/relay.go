@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetRelayHeight returns the scanned layer2 height of the given chain, ok is false if it's not initialized yet
+func (m Metis) GetRelayHeight(ctx context.Context, chainId uint64) (height uint64, ok bool, err error) {
+	const query = "SELECT `number` FROM `relay_height` WHERE `chainid`=?;"
+	if err := m.db.QueryRowxContext(ctx, query, chainId).Scan(&height); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("GetRelayHeight: %w", err)
+	}
+	return height, true, nil
+}
+
+// GetOldestUnrelayedDeposit returns the block time of the oldest pending deposit which is not linked to a relay yet,
+// ok is false if there is none.
+func (m Metis) GetOldestUnrelayedDeposit(ctx context.Context, chainId uint64) (blockTime time.Time, ok bool, err error) {
+	const query = "SELECT MIN(A.`blocktime`) FROM `deposits` AS A WHERE A.`chainid`=? AND A.`status` IN (?,?) " +
+		"AND NOT EXISTS (SELECT 1 FROM `relays` AS B WHERE B.pid=A.id);"
+	var res sql.NullTime
+	if err := m.db.QueryRowContext(ctx, query, chainId, DepositStatusUnprocessed, DepositStatusDeferred).Scan(&res); err != nil {
+		return time.Time{}, false, fmt.Errorf("GetOldestUnrelayedDeposit: %w", err)
+	}
+	return res.Time, res.Valid, nil
+}
+
+// InitRelayHeight returns the next layer2 height to scan the relays of the given chain
+func (m Metis) InitRelayHeight(ctx context.Context, chainId, defaultHeight uint64) (uint64, error) {
+	const query = "SELECT `number` FROM `relay_height` WHERE `chainid`=?;"
+
+	var height uint64
+	if err := m.db.QueryRowxContext(ctx, query, chainId).Scan(&height); err != nil {
+		if err == sql.ErrNoRows {
+			const init = "INSERT INTO `relay_height` (`chainid`,`number`) VALUES (?,?);"
+			if _, err := m.db.ExecContext(ctx, init, chainId, defaultHeight); err != nil {
+				return 0, fmt.Errorf("InitRelayHeight: init height %w", err)
+			}
+			return defaultHeight, nil
+		}
+		return 0, fmt.Errorf("InitRelayHeight: get height %w", err)
+	}
+	return height + 1, nil
+}
+
+// SaveRelays saves the relays scanned from layer2 and moves the scanned height to the tail
+func (m Metis) SaveRelays(ctx context.Context, chainId uint64, relays []*Relay, tail uint64) (err error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("SaveRelays: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("SaveRelays: rollback: %s", rollbackError)
+		}
+	}()
+
+	const insertRelayQuery = "INSERT INTO `relays` (`chainid`,`txid`,`logindex`,`height`,`blocktime`,`l1token`,`l2token`,`from`,`to`,`amount`,`failed`) " +
+		"VALUES (?,?,?,?,?,?,?,?,?,?,?) ON DUPLICATE KEY UPDATE `id`=`id`;"
+	for _, item := range relays {
+		args := []interface{}{chainId, item.Txid, item.LogIndex, item.Height, item.BlockTime, item.L1Token, item.L2Token, item.From, item.To, item.Amount, item.Failed}
+		if _, err = tx.ExecContext(ctx, insertRelayQuery, args...); err != nil {
+			return fmt.Errorf("SaveRelays: save relay: %w", err)
+		}
+	}
+
+	const updateHeightQuery = "UPDATE `relay_height` SET `number`=? WHERE `chainid`=?;"
+	if _, err = tx.ExecContext(ctx, updateHeightQuery, tail, chainId); err != nil {
+		return fmt.Errorf("SaveRelays: update height: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("SaveRelays: commit: %w", err)
+	}
+	return nil
+}
+
+// MatchRelays links the unmatched relays since the given time to their layer1 deposits.
+// The relay event doesn't carry the layer1 txid, so a relay is matched with the earliest unmatched deposit
+// which has the same token, sender, receiver and amount, and the L1 to L2 latency is recorded.
+// The relays are checked in the order of their last check time, so the unmatchable ones don't starve the newer ones.
+func (m Metis) MatchRelays(ctx context.Context, chainId uint64, since time.Time) (matched int, err error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("MatchRelays: begin tx %w", err)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			logrus.Errorf("MatchRelays: rollback: %s", rollbackError)
+		}
+	}()
+
+	const selectRelayQuery = "SELECT * FROM `relays` WHERE `chainid`=? AND `pid`=0 AND `blocktime`>=? ORDER BY `checktime`,`height`,`logindex` LIMIT 500;"
+	var relays []*Relay
+	if err = tx.SelectContext(ctx, &relays, selectRelayQuery, chainId, since); err != nil {
+		return 0, fmt.Errorf("MatchRelays: select relays: %w", err)
+	}
+
+	const selectDepositQuery = "SELECT `id`,`blocktime` FROM `deposits` AS A WHERE `chainid`=? AND `l1token`=? AND `l2token`=? AND `from`=? AND `to`=? AND `amount`=? AND `blocktime`<=? " +
+		"AND NOT EXISTS (SELECT 1 FROM `relays` AS B WHERE B.pid=A.id) ORDER BY `height`,`logindex` LIMIT 1;"
+	const updateRelayQuery = "UPDATE `relays` SET `pid`=?,`latency`=?,`checktime`=NOW() WHERE `id`=?;"
+	const checkRelayQuery = "UPDATE `relays` SET `checktime`=NOW() WHERE `id`=?;"
+	for _, item := range relays {
+		var deposit struct {
+			Id        uint64    `db:"id"`
+			BlockTime time.Time `db:"blocktime"`
+		}
+		args := []interface{}{chainId, item.L1Token, item.L2Token, item.From, item.To, item.Amount, item.BlockTime}
+		if err = tx.GetContext(ctx, &deposit, selectDepositQuery, args...); err != nil {
+			if err != sql.ErrNoRows {
+				return 0, fmt.Errorf("MatchRelays: select deposit: %w", err)
+			}
+			if _, err = tx.ExecContext(ctx, checkRelayQuery, item.Id); err != nil {
+				return 0, fmt.Errorf("MatchRelays: check relay: %w", err)
+			}
+			continue
+		}
+
+		latency := max(item.BlockTime.Sub(deposit.BlockTime), 0) / time.Second
+		if _, err = tx.ExecContext(ctx, updateRelayQuery, deposit.Id, uint64(latency), item.Id); err != nil {
+			return 0, fmt.Errorf("MatchRelays: update relay: %w", err)
+		}
+		matched++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("MatchRelays: commit: %w", err)
+	}
+	return matched, nil
+}
+
+// IgnoreFailedRelays ignores the unprocessed deposits which are failed to relay and returned to layer1
+func (m Metis) IgnoreFailedRelays(ctx context.Context, chainId uint64) (int64, error) {
+	const query = "UPDATE `deposits` AS A INNER JOIN `relays` AS B ON B.pid=A.id SET A.`status`=? WHERE A.`chainid`=? AND A.`status`=? AND B.`failed`=1;"
+	res, err := m.db.ExecContext(ctx, query, DepositStatusIgnore, chainId, DepositStatusUnprocessed)
+	if err != nil {
+		return 0, fmt.Errorf("IgnoreFailedRelays: %w", err)
+	}
+	count, _ := res.RowsAffected()
+	return count, nil
+}
+
+// IgnoreUnrelayedDeposits ignores the unprocessed deposits before the given time which are neither linked to a relay
+// nor have an unmatched relay of the same token, sender, receiver and amount.
+func (m Metis) IgnoreUnrelayedDeposits(ctx context.Context, chainId uint64, before time.Time) (int64, error) {
+	const query = "UPDATE `deposits` AS A SET A.`status`=? WHERE A.`chainid`=? AND A.`status`=? AND A.`blocktime`<? " +
+		"AND NOT EXISTS (SELECT 1 FROM `relays` AS B WHERE B.pid=A.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM `relays` AS C WHERE C.`chainid`=A.`chainid` AND C.pid=0 AND C.`l1token`=A.`l1token` AND C.`l2token`=A.`l2token` " +
+		"AND C.`from`=A.`from` AND C.`to`=A.`to` AND C.`amount`=A.`amount`);"
+	res, err := m.db.ExecContext(ctx, query, DepositStatusIgnore, chainId, DepositStatusUnprocessed, before)
+	if err != nil {
+		return 0, fmt.Errorf("IgnoreUnrelayedDeposits: %w", err)
+	}
+	count, _ := res.RowsAffected()
+	return count, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ShadowDrip is the decision of a dry-run faucet for a deposit, nothing is signed or sent
+type ShadowDrip struct {
+	Name      string       `db:"name"`
+	Pid       uint64       `db:"pid"`
+	Policy    string       `db:"policy"`
+	Decision  DripDecision `db:"decision"`
+	Reason    string       `db:"reason"`
+	Amount    float64      `db:"amount"`
+	USD       float64      `db:"usd"`
+	RiskScore *uint8       `db:"riskscore"`
+	// the times the shadow run defers the deposit, and its next retry time
+	Attempts  uint       `db:"attempts"`
+	NextRetry *time.Time `db:"nextretry"`
+	CreatedAt time.Time  `db:"ctime"`
+}
+
+// GetShadowDepositStream returns the relayed deposits which are not decided by the live faucet yet,
+// and either haven't been decided by the shadow run or are skipped by it, the skipped ones are decided again like the live faucet,
+// and the ones deferred by the shadow run are decided again once their retry time is due.
+func (m Metis) GetShadowDepositStream(ctx context.Context, chainId uint64, name string) <-chan DepositTxStream {
+	const query = "SELECT A.* FROM `deposits` AS A INNER JOIN `relays` AS B ON B.pid=A.id LEFT JOIN `shadow_drips` AS C ON C.pid=A.id AND C.`name`=? " +
+		"WHERE A.`chainid`=? AND A.`status` IN (?,?) AND A.`reorged`=0 AND B.`failed`=0 AND (C.`pid` IS NULL OR C.`decision`=? OR (C.`decision`=? AND C.`nextretry`<=?)) " +
+		"AND A.`id`>? ORDER BY A.`id` LIMIT ?;"
+	return m.depositStream(ctx, query, name, chainId, DepositStatusUnprocessed, DepositStatusDeferred, DecisionSkip, DecisionDefer, time.Now())
+}
+
+// GetShadowDrip returns the decision of the shadow run for the deposit, it's nil if the deposit is not decided yet
+func (m Metis) GetShadowDrip(ctx context.Context, name string, pid uint64) (*ShadowDrip, error) {
+	const query = "SELECT * FROM `shadow_drips` WHERE `name`=? AND `pid`=?;"
+	var res ShadowDrip
+	if err := m.db.GetContext(ctx, &res, query, name, pid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetShadowDrip: %w", err)
+	}
+	return &res, nil
+}
+
+// SaveShadowDrip saves the decision of the shadow run, the decision of a skipped or deferred deposit is replaced
+func (m Metis) SaveShadowDrip(ctx context.Context, drip *ShadowDrip) error {
+	const query = "INSERT INTO `shadow_drips` (`name`,`pid`,`policy`,`decision`,`reason`,`amount`,`usd`,`riskscore`,`attempts`,`nextretry`) VALUES (?,?,?,?,?,?,?,?,?,?) " +
+		"ON DUPLICATE KEY UPDATE `policy`=VALUES(`policy`),`decision`=VALUES(`decision`),`reason`=VALUES(`reason`),`amount`=VALUES(`amount`),`usd`=VALUES(`usd`)," +
+		"`riskscore`=VALUES(`riskscore`),`attempts`=VALUES(`attempts`),`nextretry`=VALUES(`nextretry`),`ctime`=CURRENT_TIMESTAMP;"
+	if _, err := m.db.ExecContext(ctx, query, drip.Name, drip.Pid, drip.Policy, drip.Decision, drip.Reason, drip.Amount, drip.USD, drip.RiskScore, drip.Attempts, drip.NextRetry); err != nil {
+		return fmt.Errorf("SaveShadowDrip: %w", err)
+	}
+	return nil
+}
+
+// GetShadowSpent sums the would-be drips of the shadow run since the time, the drips of all the policies are summed if the policy is empty
+func (m Metis) GetShadowSpent(ctx context.Context, name, policy string, since time.Time) (metis, usd float64, err error) {
+	query := "SELECT COALESCE(SUM(`amount`),0), COALESCE(SUM(`usd`),0) FROM `shadow_drips` WHERE `name`=? AND `decision`=?"
+	args := []interface{}{name, DecisionDrip}
+	if !since.IsZero() {
+		query += " AND `ctime`>=NOW()-INTERVAL ? MICROSECOND"
+		args = append(args, elapsedSince(since))
+	}
+	if policy != "" {
+		query += " AND `policy`=?"
+		args = append(args, policy)
+	}
+	if err := m.db.QueryRowContext(ctx, query+";", args...).Scan(&metis, &usd); err != nil {
+		return 0, 0, fmt.Errorf("GetShadowSpent: %w", err)
+	}
+	return metis, usd, nil
+}
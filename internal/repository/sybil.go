@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SybilSignals are the clustering signals of a deposit from the deposits table
+type SybilSignals struct {
+	// the distinct recipients funded by the layer1 sender in the window
+	SenderRecipients int
+	// the distinct recipients funded by the layer1 sender in the burst around the deposit
+	BurstRecipients int
+	// the distinct layer1 senders depositing the same token and amount in the burst around the deposit,
+	// it's a proxy of the scripted deposits
+	SameAmountSenders int
+	// the distinct layer1 senders funded by the same account as the sender in the window
+	FunderSenders int
+}
+
+// GetSybilSignals collects the signals of the deposits around the deposit block time
+func (m Metis) GetSybilSignals(ctx context.Context, deposit *Deposit, window, burst time.Duration) (*SybilSignals, error) {
+	var res SybilSignals
+	var err error
+
+	if res.SenderRecipients, err = m.CountSenderRecipients(ctx, deposit, window); err != nil {
+		return nil, fmt.Errorf("GetSybilSignals: %w", err)
+	}
+	if res.BurstRecipients, err = m.CountSenderRecipients(ctx, deposit, burst); err != nil {
+		return nil, fmt.Errorf("GetSybilSignals: burst: %w", err)
+	}
+
+	const amountQuery = "SELECT COUNT(DISTINCT `from`) FROM `deposits` WHERE `chainid`=? AND `l1token`=? AND `amount`=? AND `blocktime` BETWEEN ? AND ?;"
+	if err := m.db.QueryRowContext(ctx, amountQuery, deposit.ChainId, deposit.L1Token, deposit.Amount,
+		deposit.BlockTime.Add(-burst), deposit.BlockTime.Add(burst)).Scan(&res.SameAmountSenders); err != nil {
+		return nil, fmt.Errorf("GetSybilSignals: same amount senders: %w", err)
+	}
+
+	if deposit.Funder != nil && *deposit.Funder != "" {
+		const funderQuery = "SELECT COUNT(DISTINCT `from`) FROM `deposits` WHERE `chainid`=? AND `funder`=? AND `blocktime` BETWEEN ? AND ?;"
+		if err := m.db.QueryRowContext(ctx, funderQuery, deposit.ChainId, *deposit.Funder,
+			deposit.BlockTime.Add(-window), deposit.BlockTime.Add(window)).Scan(&res.FunderSenders); err != nil {
+			return nil, fmt.Errorf("GetSybilSignals: funder senders: %w", err)
+		}
+	}
+	return &res, nil
+}
+
+// GetFunder returns the traced funder of the layer1 sender from its other deposits, it's nil if it's not traced yet
+func (m Metis) GetFunder(ctx context.Context, from string) (*string, error) {
+	const query = "SELECT `funder` FROM `deposits` WHERE `from`=? AND `funder` IS NOT NULL LIMIT 1;"
+	var funder string
+	if err := m.db.QueryRowContext(ctx, query, from).Scan(&funder); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetFunder: %w", err)
+	}
+	return &funder, nil
+}
+
+// SetFunder saves the traced layer1 funder of the deposit sender
+func (m Metis) SetFunder(ctx context.Context, id uint64, funder string) error {
+	const query = "UPDATE `deposits` SET `funder`=? WHERE `id`=?;"
+	if _, err := m.db.ExecContext(ctx, query, funder, id); err != nil {
+		return fmt.Errorf("SetFunder: %w", err)
+	}
+	return nil
+}
+
+// CountSenderRecipients returns the distinct recipients funded by the layer1 sender of the deposit in the window around its block time
+func (m Metis) CountSenderRecipients(ctx context.Context, deposit *Deposit, window time.Duration) (int, error) {
+	const query = "SELECT COUNT(DISTINCT `to`) FROM `deposits` WHERE `chainid`=? AND `from`=? AND `blocktime` BETWEEN ? AND ?;"
+	var count int
+	if err := m.db.QueryRowContext(ctx, query, deposit.ChainId, deposit.From,
+		deposit.BlockTime.Add(-window), deposit.BlockTime.Add(window)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("CountSenderRecipients: %w", err)
+	}
+	return count, nil
+}
+
+// SetRiskScore saves the sybil risk score of the deposit
+func (m Metis) SetRiskScore(ctx context.Context, id uint64, score uint8) error {
+	const query = "UPDATE `deposits` SET `riskscore`=? WHERE `id`=?;"
+	if _, err := m.db.ExecContext(ctx, query, score, id); err != nil {
+		return fmt.Errorf("SetRiskScore: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// Backfill re-syncs the events in [startHeight, endHeight] idempotently without moving the height cursor
+func (s *DataSync) Backfill(basectx context.Context, startHeight, endHeight uint64) (*repository.UpsertedEvents, error) {
+	if startHeight > endHeight {
+		return nil, fmt.Errorf("Backfill: invalid range %d-%d", startHeight, endHeight)
+	}
+
+	targetHeight, err := s.getTargetHeight(basectx)
+	if err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+	if endHeight > targetHeight {
+		logrus.Warnf("Backfill: the end height %d is not confirmed yet, clamped to %d", endHeight, targetHeight)
+		endHeight = targetHeight
+	}
+	if startHeight > endHeight {
+		return nil, fmt.Errorf("Backfill: the start height %d is higher than the confirmed height %d", startHeight, endHeight)
+	}
+
+	// the backfill starts from the persisted span, and learns on its own copy without saving it
+	persisted, err := s.Repositroy.GetRangeSpan(basectx)
+	if err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+	span := newRangeSpan(persisted, s.RangeSync)
+	if err := s.initDefaultChainId(basectx); err != nil {
+		return nil, fmt.Errorf("Backfill: %w", err)
+	}
+
+	var total = new(repository.UpsertedEvents)
+	for fromHeight := startHeight; fromHeight <= endHeight; {
+		toHeight := min(fromHeight+span.get(), endHeight)
+		logrus.Infof("Backfilling from %d to %d", fromHeight, toHeight)
+
+		events, err := func() (*rangeEvents, error) {
+			newctx, cancel := context.WithTimeout(basectx, time.Minute*10)
+			defer cancel()
+			return s.fetchEvents(newctx, span, fromHeight, toHeight)
+		}()
+		if err != nil {
+			return total, fmt.Errorf("Backfill: %w", err)
+		}
+
+		res, err := s.Repositroy.SaveBackfilledData(basectx, events.deposits, events.withdrawals)
+		if err != nil {
+			return total, fmt.Errorf("Backfill: %w", err)
+		}
+		total.Add(res)
+
+		fromHeight = toHeight + 1
+	}
+	return total, nil
+}
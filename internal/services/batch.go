@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/metis-devops/metis-bridge-rebate/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// dripBatch collects the eligible drips of a loop to send them in one disperse transaction
+type dripBatch struct {
+	deposits   []*repository.Deposit
+	drips      []*repository.Drip
+	recipients []common.Address
+	amounts    []*big.Int
+	total      big.Int
+}
+
+func (b *dripBatch) add(deposit *repository.Deposit, drip *repository.Drip, amount *big.Int) {
+	drip.Rawtx = []byte{}
+	b.deposits = append(b.deposits, deposit)
+	b.drips = append(b.drips, drip)
+	b.recipients = append(b.recipients, common.HexToAddress(deposit.To))
+	b.amounts = append(b.amounts, amount)
+	b.total.Add(&b.total, amount)
+}
+
+// remove takes the drip of the deposit out of the batch, it returns nil if it's not in the batch
+func (b *dripBatch) remove(pid uint64) (*repository.Deposit, *repository.Drip) {
+	for idx, drip := range b.drips {
+		if drip.Pid != pid {
+			continue
+		}
+		deposit := b.deposits[idx]
+		b.total.Sub(&b.total, b.amounts[idx])
+		b.deposits = append(b.deposits[:idx], b.deposits[idx+1:]...)
+		b.drips = append(b.drips[:idx], b.drips[idx+1:]...)
+		b.recipients = append(b.recipients[:idx], b.recipients[idx+1:]...)
+		b.amounts = append(b.amounts[:idx], b.amounts[idx+1:]...)
+		return deposit, drip
+	}
+	return nil, nil
+}
+
+func (s *Faucet) batchMode() bool {
+	return s.DisperseContract != (common.Address{})
+}
+
+// sendBatch sends the collected drips in one disperse transaction,
+// the drips exceeding the rate limits are ignored and the rest are signed again.
+func (s *Faucet) sendBatch(ctx context.Context, w *Wallet, batch *dripBatch) error {
+	for len(batch.drips) > 0 {
+		err := s.trySendBatch(ctx, w, batch)
+		var limited repository.ErrDripLimited
+		if !errors.As(err, &limited) {
+			return err
+		}
+		deposit, drip := batch.remove(limited.Pid)
+		if deposit == nil {
+			return err
+		}
+		logrus.Infof("Don't need to give a drip to %s: %s", deposit.To, limited)
+		if err := s.saveDecision(ctx, nil, drip.Policy, deposit, repository.DecisionIgnore, ReasonRateLimited, limited.Error(), 0, 0); err != nil {
+			return fmt.Errorf("sendBatch: %w", err)
+		}
+		if err := s.Repositroy.NewDrip(ctx, deposit, nil); err != nil {
+			return fmt.Errorf("sendBatch: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Faucet) trySendBatch(ctx context.Context, w *Wallet, batch *dripBatch) error {
+	if err := s.checkBatchBalance(ctx, w, batch); err != nil {
+		return fmt.Errorf("sendBatch: %w", err)
+	}
+	data, err := s.disperseABI.Pack("disperseEther", batch.recipients, batch.amounts)
+	if err != nil {
+		return fmt.Errorf("sendBatch: pack: %w", err)
+	}
+	tx, err := s.makeDripTx(ctx, w, w.nonce.next, s.DisperseContract, &batch.total, data)
+	if err != nil {
+		return fmt.Errorf("sendBatch: %w", err)
+	}
+	rawtx, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("sendBatch: %w", err)
+	}
+
+	for _, drip := range batch.drips {
+		drip.Txid = tx.Hash().Hex()
+		drip.From = w.Account.Hex()
+		drip.Nonce = tx.Nonce()
+	}
+	record := &repository.DripBatch{
+		ChainId: s.L2ChainId,
+		Txid:    tx.Hash().Hex(),
+		From:    w.Account.Hex(),
+		Nonce:   tx.Nonce(),
+		Rawtx:   rawtx,
+	}
+	if err := s.Repositroy.NewDripBatch(ctx, record, batch.drips); err != nil {
+		return fmt.Errorf("sendBatch: %w", err)
+	}
+	w.nonce.next++
+
+	logrus.Infof("Drip batch %d: send %f Metis to %d recipients [ Nonce %d Tx %s ]",
+		record.Id, utils.ToEther(&batch.total), len(batch.drips), record.Nonce, record.Txid)
+	// the batch is persisted already, it will be broadcasted again by the drip checking
+	if err := s.sendTx(ctx, tx); err != nil {
+		return fmt.Errorf("sendBatch: send %s: %w", record.Txid, err)
+	}
+	return nil
+}
+
+// checkBatchBalance checks the wallet keeps its threshold after sending the batch total
+func (s *Faucet) checkBatchBalance(basectx context.Context, w *Wallet, batch *dripBatch) error {
+	newctx, cancel := context.WithTimeout(basectx, time.Second*5)
+	defer cancel()
+
+	balance, err := s.MetisClient.BalanceAt(newctx, w.Account, nil)
+	if err != nil {
+		return fmt.Errorf("checkBatchBalance: get balance of %s: %w", w.Account, err)
+	}
+	remaining := utils.ToEther(new(big.Int).Sub(balance, &batch.total))
+	if threshold := s.walletThreshold(w); remaining < threshold {
+		return fmt.Errorf("checkBatchBalance: insufficient balance of %s: %f Metis left after the batch of %f Metis < %f Metis",
+			w.Account, remaining, utils.ToEther(&batch.total), threshold)
+	}
+	return nil
+}
+
+// getBatchReceived returns the drips received by the disperse events in the receipt, keyed by the deposit id.
+// An event is matched with a drip of the same recipient and amount, and every drip is matched once.
+func (s *Faucet) getBatchReceived(receipt *types.Receipt, drips []*repository.Drip) map[uint64]bool {
+	received := make(map[uint64]bool)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return received
+	}
+
+	for _, log := range receipt.Logs {
+		// the DisperseFailed events are skipped
+		if log.Address != s.DisperseContract || len(log.Topics) == 0 || log.Topics[0] != s.disperseABI.Events["Dispersed"].ID {
+			continue
+		}
+		event, err := s.disperse.ParseDispersed(*log)
+		if err != nil {
+			logrus.Warnf("getBatchReceived: invalid Dispersed event %d [ Tx %s ]: %s", log.Index, receipt.TxHash, err)
+			continue
+		}
+		recipient := strings.ToLower(event.Recipient.Hex())
+		for _, drip := range drips {
+			if !received[drip.Pid] && drip.To == recipient && sameAmount(drip.Amount, event.Amount) {
+				received[drip.Pid] = true
+				break
+			}
+		}
+	}
+	return received
+}
+
+// sameAmount compares the drip amount with the wei amount, the drip amount is saved in ether as a float
+func sameAmount(ether float64, wei *big.Int) bool {
+	expected := utils.ToEther(wei)
+	return math.Abs(expected-ether) <= expected*1e-9
+}
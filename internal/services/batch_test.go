@@ -0,0 +1,93 @@
+package services
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metis-devops/metis-bridge-rebate/internal/goabi"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+func TestGetBatchReceived(t *testing.T) {
+	disperseABI, err := goabi.DisperseMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		contract = common.HexToAddress("0x1000")
+		alice    = common.HexToAddress("0xA11CE")
+		bob      = common.HexToAddress("0xB0B")
+		carol    = common.HexToAddress("0xCA201")
+	)
+	disperse, err := goabi.NewDisperseFilterer(contract, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Faucet{DisperseContract: contract, disperseABI: disperseABI, disperse: disperse}
+
+	newLog := func(address common.Address, event string, recipient common.Address, amount int64) *types.Log {
+		data, err := disperseABI.Events[event].Inputs.NonIndexed().Pack(big.NewInt(amount))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &types.Log{
+			Address: address,
+			Topics:  []common.Hash{disperseABI.Events[event].ID, common.BytesToHash(recipient.Bytes())},
+			Data:    data,
+		}
+	}
+	lower := func(address common.Address) string {
+		return strings.ToLower(address.Hex())
+	}
+	drips := []*repository.Drip{
+		{Pid: 1, To: lower(alice), Amount: 0.01},
+		{Pid: 2, To: lower(alice), Amount: 0.02},
+		{Pid: 3, To: lower(bob), Amount: 0.01},
+		{Pid: 4, To: lower(carol), Amount: 0.01},
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		Logs: []*types.Log{
+			newLog(contract, "Dispersed", alice, 2e16),
+			newLog(contract, "DisperseFailed", bob, 1e16),
+			// the events from other contracts are ignored
+			newLog(common.HexToAddress("0x2000"), "Dispersed", carol, 1e16),
+			// the amount doesn't match any drip of the recipient
+			newLog(contract, "Dispersed", carol, 3e16),
+		},
+	}
+
+	received := s.getBatchReceived(receipt, drips)
+	if len(received) != 1 || !received[2] {
+		t.Errorf("getBatchReceived() = %v, want only the drip 2", received)
+	}
+
+	receipt.Status = types.ReceiptStatusFailed
+	if received := s.getBatchReceived(receipt, drips); len(received) != 0 {
+		t.Errorf("getBatchReceived() = %v, want none for a reverted batch", received)
+	}
+}
+
+func TestDripBatchRemove(t *testing.T) {
+	batch := new(dripBatch)
+	for id, amount := range []int64{1, 2, 3} {
+		deposit := &repository.Deposit{Id: uint64(id + 1), To: common.BigToAddress(big.NewInt(int64(id + 1))).Hex()}
+		batch.add(deposit, &repository.Drip{Pid: deposit.Id, To: deposit.To}, big.NewInt(amount))
+	}
+
+	if deposit, drip := batch.remove(2); deposit == nil || deposit.Id != 2 || drip.Pid != 2 {
+		t.Fatalf("remove() = %v, want deposit 2", deposit)
+	}
+	if deposit, _ := batch.remove(2); deposit != nil {
+		t.Errorf("remove() = %v, want nil for a removed drip", deposit)
+	}
+	if len(batch.drips) != 2 || len(batch.recipients) != 2 || len(batch.amounts) != 2 || len(batch.deposits) != 2 {
+		t.Fatalf("remove() left %d drips %d recipients %d amounts", len(batch.drips), len(batch.recipients), len(batch.amounts))
+	}
+	if batch.drips[1].Pid != 3 || batch.amounts[1].Int64() != 3 || batch.total.Int64() != 4 {
+		t.Errorf("remove() left drip %d amount %s total %s, want drip 3 amount 3 total 4", batch.drips[1].Pid, batch.amounts[1], &batch.total)
+	}
+}
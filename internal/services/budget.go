@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/services/policy"
+)
+
+type budgetKey struct {
+	policy string
+	window time.Duration
+}
+
+type budgetSpent struct {
+	metis, usd float64
+}
+
+// budgetTracker keeps the spent budgets of a loop, they're loaded from the database at the first use,
+// and the drips planned in the loop are added on top of them.
+type budgetTracker struct {
+	now   time.Time
+	spent map[budgetKey]*budgetSpent
+	load  func(ctx context.Context, policy string, since time.Time) (metis, usd float64, err error)
+}
+
+func (s *Faucet) newBudgetTracker(now time.Time) *budgetTracker {
+	return &budgetTracker{
+		now:   now,
+		spent: make(map[budgetKey]*budgetSpent),
+		load: func(ctx context.Context, policy string, since time.Time) (float64, float64, error) {
+			if s.DryRun != "" {
+				return s.Repositroy.GetShadowSpent(ctx, s.DryRun, policy, since)
+			}
+			return s.Repositroy.GetDripSpent(ctx, s.L2ChainId, policy, since)
+		},
+	}
+}
+
+func (b *budgetTracker) get(ctx context.Context, key budgetKey, budget policy.Budget) (*budgetSpent, error) {
+	if spent, ok := b.spent[key]; ok {
+		return spent, nil
+	}
+	metis, usd, err := b.load(ctx, key.policy, budget.Since(b.now))
+	if err != nil {
+		return nil, err
+	}
+	spent := &budgetSpent{metis: metis, usd: usd}
+	b.spent[key] = spent
+	return spent, nil
+}
+
+// check returns ErrorBudgetExhausted if the drip exceeds any of the budgets, the empty policy is the global scope
+func (b *budgetTracker) check(ctx context.Context, scope string, budgets []policy.Budget, metis, usd float64) error {
+	for _, budget := range budgets {
+		spent, err := b.get(ctx, budgetKey{policy: scope, window: budget.Window}, budget)
+		if err != nil {
+			return err
+		}
+		if (budget.MaxMetis > 0 && spent.metis+metis > budget.MaxMetis) || (budget.MaxUSD > 0 && spent.usd+usd > budget.MaxUSD) {
+			name := "global"
+			if scope != "" {
+				name = fmt.Sprintf("policy %s", scope)
+			}
+			msg := fmt.Sprintf("%s budget %s is exhausted: spent %f Metis %f USD", name, budget, spent.metis, spent.usd)
+			if until := budget.Until(b.now); !until.IsZero() {
+				msg += fmt.Sprintf(", resume at %s", until.Format(time.RFC3339))
+			}
+			return ErrorBudgetExhausted{msg: msg, global: scope == ""}
+		}
+	}
+	return nil
+}
+
+// spend adds the planned drip to the budgets, the budgets of the same window share the spending so it's added once
+func (b *budgetTracker) spend(scope string, budgets []policy.Budget, metis, usd float64) {
+	added := make(map[budgetKey]bool, len(budgets))
+	for _, budget := range budgets {
+		key := budgetKey{policy: scope, window: budget.Window}
+		if added[key] {
+			continue
+		}
+		added[key] = true
+		if spent, ok := b.spent[key]; ok {
+			spent.metis += metis
+			spent.usd += usd
+		}
+	}
+}
+
+// checkBudgets checks the global budgets and the policy budgets for the drip
+func (s *Faucet) checkBudgets(ctx context.Context, tracker *budgetTracker, pc *policy.Drip, metis, usd float64) error {
+	if err := tracker.check(ctx, "", s.Budgets, metis, usd); err != nil {
+		return err
+	}
+	return tracker.check(ctx, pc.Name, pc.Budgets, metis, usd)
+}
+
+func (s *Faucet) spendBudgets(tracker *budgetTracker, pc *policy.Drip, metis, usd float64) {
+	tracker.spend("", s.Budgets, metis, usd)
+	tracker.spend(pc.Name, pc.Budgets, metis, usd)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/services/policy"
+)
+
+func TestBudgetTracker(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 10, 30, 0, 0, time.UTC)
+	stored := map[time.Time]float64{
+		// the hourly window
+		time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC): 8,
+		// the lifetime window
+		{}: 95,
+	}
+	tracker := &budgetTracker{
+		now:   now,
+		spent: make(map[budgetKey]*budgetSpent),
+		load: func(ctx context.Context, policy string, since time.Time) (float64, float64, error) {
+			metis, ok := stored[since]
+			if !ok {
+				t.Fatalf("unexpected window start %s", since)
+			}
+			return metis, metis * 2, nil
+		},
+	}
+	budgets := []policy.Budget{
+		{Window: time.Hour, MaxMetis: 10},
+		{MaxUSD: 200},
+	}
+
+	tests := []struct {
+		name      string
+		metis     float64
+		exhausted bool
+	}{
+		{"within the budgets", 1, false},
+		{"exceeds the hourly metis budget", 1.5, true},
+		{"fits the rest of the hourly budget", 1, false},
+		{"hourly budget is used up", 0.1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tracker.check(context.Background(), "Default", budgets, tt.metis, tt.metis*2)
+			var exhausted ErrorBudgetExhausted
+			if got := errors.As(err, &exhausted); got != tt.exhausted {
+				t.Fatalf("check() error = %v, exhausted %v", err, tt.exhausted)
+			}
+			if exhausted.global {
+				t.Errorf("check() reports a policy budget as global")
+			}
+			if err == nil {
+				tracker.spend("Default", budgets, tt.metis, tt.metis*2)
+			}
+		})
+	}
+
+	// the global scope is tracked apart from the policy, 190 USD is spent before the loop
+	global := []policy.Budget{{MaxUSD: 200}}
+	if err := tracker.check(context.Background(), "", global, 4, 8); err != nil {
+		t.Fatalf("check() error = %v, want nil", err)
+	}
+	tracker.spend("", global, 4, 8)
+	var exhausted ErrorBudgetExhausted
+	if err := tracker.check(context.Background(), "", global, 1.5, 3); !errors.As(err, &exhausted) || !exhausted.global {
+		t.Errorf("check() error = %v, want the global budget exhausted", err)
+	}
+}
+
+func TestBudgetTracker_SameWindow(t *testing.T) {
+	tracker := &budgetTracker{
+		now:   time.Date(2024, time.March, 1, 10, 30, 0, 0, time.UTC),
+		spent: make(map[budgetKey]*budgetSpent),
+		load: func(ctx context.Context, policy string, since time.Time) (float64, float64, error) {
+			return 0, 0, nil
+		},
+	}
+	// the metis and usd limits of one window, the spending is shared and must be added once
+	budgets := []policy.Budget{
+		{Window: time.Hour * 24, MaxMetis: 10},
+		{Window: time.Hour * 24, MaxUSD: 1000},
+	}
+	for i := 0; i < 10; i++ {
+		if err := tracker.check(context.Background(), "Default", budgets, 1, 2); err != nil {
+			t.Fatalf("check() drip %d error = %v, want nil", i, err)
+		}
+		tracker.spend("Default", budgets, 1, 2)
+	}
+	var exhausted ErrorBudgetExhausted
+	if err := tracker.check(context.Background(), "Default", budgets, 1, 2); !errors.As(err, &exhausted) {
+		t.Errorf("check() error = %v, want the daily budget exhausted", err)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/metis-devops/metis-bridge-rebate/internal/goabi"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+type chainIDLog struct {
+	height   uint64
+	logIndex uint
+	chainId  uint64
+}
+
+// erc20ChainIDs indexes the ERC20ChainID events by txid,
+// the event carries the destination chain id of the erc20 deposit in the same transaction.
+type erc20ChainIDs map[string][]chainIDLog
+
+func (c erc20ChainIDs) add(event *goabi.L1StandardBridgeERC20ChainID, removed bool) {
+	txid := event.Raw.TxHash.Hex()
+	logs := c[txid][:0]
+	for _, item := range c[txid] {
+		if item.logIndex != event.Raw.Index {
+			logs = append(logs, item)
+		}
+	}
+	if !removed {
+		logs = append(logs, chainIDLog{height: event.Raw.BlockNumber, logIndex: event.Raw.Index, chainId: event.Chainid.Uint64()})
+	}
+	if len(logs) == 0 {
+		delete(c, txid)
+		return
+	}
+	c[txid] = logs
+}
+
+// assign sets the chain id of the erc20 deposits with the nearest ERC20ChainID event after the deposit event,
+// or the nearest one before it, the deposits without any ERC20ChainID event go to the default chain.
+func (c erc20ChainIDs) assign(deposits []*repository.Deposit, defaultChainId uint64) {
+	for _, dpt := range deposits {
+		if dpt.ChainId != 0 {
+			continue
+		}
+
+		var after, before *chainIDLog
+		logs := c[dpt.Txid]
+		for idx := range logs {
+			item := &logs[idx]
+			if item.logIndex > dpt.LogIndex && (after == nil || item.logIndex < after.logIndex) {
+				after = item
+			}
+			if item.logIndex < dpt.LogIndex && (before == nil || item.logIndex > before.logIndex) {
+				before = item
+			}
+		}
+
+		switch {
+		case after != nil:
+			dpt.ChainId = after.chainId
+		case before != nil:
+			dpt.ChainId = before.chainId
+		default:
+			dpt.ChainId = defaultChainId
+		}
+	}
+}
+
+// prune removes the events not higher than the given height
+func (c erc20ChainIDs) prune(height uint64) {
+	for txid, logs := range c {
+		if len(logs) > 0 && logs[0].height <= height {
+			delete(c, txid)
+		}
+	}
+}
+
+func (s *DataSync) filterERC20ChainIDs(filterOption *bind.FilterOpts) (erc20ChainIDs, error) {
+	iter, err := s.Bridge.FilterERC20ChainID(filterOption)
+	if err != nil {
+		return nil, fmt.Errorf("filterERC20ChainIDs: %w", err)
+	}
+	defer iter.Close()
+
+	var chainIDs = make(erc20ChainIDs)
+	for iter.Next() {
+		chainIDs.add(iter.Event, false)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("filterERC20ChainIDs: %w", err)
+	}
+	return chainIDs, nil
+}
+
+// initDefaultChainId loads the default destination chain id of the bridge,
+// and assigns it to the legacy deposits synced before the chain id is recorded.
+func (s *DataSync) initDefaultChainId(basectx context.Context) error {
+	if s.defaultChainId != 0 {
+		return nil
+	}
+
+	chainId, err := s.Bridge.DEFAULTCHAINID(&bind.CallOpts{Context: basectx})
+	if err != nil {
+		return fmt.Errorf("initDefaultChainId: %w", err)
+	}
+	s.defaultChainId = chainId.Uint64()
+
+	if err := s.Repositroy.FillDepositChainId(basectx, s.defaultChainId); err != nil {
+		return fmt.Errorf("initDefaultChainId: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metis-devops/metis-bridge-rebate/internal/goabi"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+func newChainIDEvent(txid string, index uint, chainId int64) *goabi.L1StandardBridgeERC20ChainID {
+	return &goabi.L1StandardBridgeERC20ChainID{
+		Chainid: big.NewInt(chainId),
+		Raw:     types.Log{TxHash: common.HexToHash(txid), Index: index, BlockNumber: 10},
+	}
+}
+
+func TestERC20ChainIDs_Assign(t *testing.T) {
+	chainIDs := make(erc20ChainIDs)
+	chainIDs.add(newChainIDEvent("0x01", 3, 1088), false)
+	chainIDs.add(newChainIDEvent("0x01", 6, 1100), false)
+	chainIDs.add(newChainIDEvent("0x02", 1, 1100), false)
+	chainIDs.add(newChainIDEvent("0x03", 5, 1100), false)
+	chainIDs.add(newChainIDEvent("0x03", 5, 1100), true)
+
+	deposits := []*repository.Deposit{
+		{Txid: common.HexToHash("0x01").Hex(), LogIndex: 2},
+		{Txid: common.HexToHash("0x01").Hex(), LogIndex: 5},
+		{Txid: common.HexToHash("0x02").Hex(), LogIndex: 4},
+		{Txid: common.HexToHash("0x03").Hex(), LogIndex: 4},
+		{Txid: common.HexToHash("0x04").Hex(), LogIndex: 0, ChainId: 1200},
+	}
+	chainIDs.assign(deposits, 1)
+
+	for idx, want := range []uint64{1088, 1100, 1100, 1, 1200} {
+		if got := deposits[idx].ChainId; got != want {
+			t.Errorf("deposit %d chain id = %d, want %d", idx, got, want)
+		}
+	}
+
+	chainIDs.prune(10)
+	if len(chainIDs) != 0 {
+		t.Errorf("prune() should remove all the events")
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ConfirmationMode decides how DataSync gets the height which is safe to sync to
+type ConfirmationMode string
+
+const (
+	// the latest height minus a fixed confirmation number
+	ConfirmationByNumber ConfirmationMode = "number"
+	// the height of the safe block tag
+	ConfirmationBySafe ConfirmationMode = "safe"
+	// the height of the finalized block tag
+	ConfirmationByFinalized ConfirmationMode = "finalized"
+)
+
+func (m ConfirmationMode) Valid() bool {
+	switch m {
+	case ConfirmationByNumber, ConfirmationBySafe, ConfirmationByFinalized:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m ConfirmationMode) blockTag() *big.Int {
+	switch m {
+	case ConfirmationBySafe:
+		return big.NewInt(int64(rpc.SafeBlockNumber))
+	case ConfirmationByFinalized:
+		return big.NewInt(int64(rpc.FinalizedBlockNumber))
+	default:
+		return nil
+	}
+}
+
+// getTargetHeight returns the latest height which has enough confirmations
+func (s *DataSync) getTargetHeight(basectx context.Context) (uint64, error) {
+	newctx, cancle := context.WithTimeout(basectx, time.Second*10)
+	defer cancle()
+
+	if tag := s.ConfirmationMode.blockTag(); tag != nil {
+		header, err := s.EtherClient.HeaderByNumber(newctx, tag)
+		if err != nil {
+			return 0, fmt.Errorf("getTargetHeight: get %s header: %w", s.ConfirmationMode, err)
+		}
+		return header.Number.Uint64(), nil
+	}
+
+	block, err := s.EtherClient.BlockNumber(newctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.confirmedByNumber(block), nil
+}
+
+// getConfirmedHeight returns the confirmed height when a new head arrives
+func (s *DataSync) getConfirmedHeight(basectx context.Context, head uint64) (uint64, error) {
+	if s.ConfirmationMode.blockTag() != nil {
+		return s.getTargetHeight(basectx)
+	}
+	return s.confirmedByNumber(head), nil
+}
+
+func (s *DataSync) confirmedByNumber(head uint64) uint64 {
+	if head > s.ConfirmationNumber {
+		return head - s.ConfirmationNumber
+	}
+	return head
+}
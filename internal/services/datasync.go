@@ -9,29 +9,57 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/islishude/bigint"
 	"github.com/metis-devops/metis-bridge-rebate/internal/goabi"
 	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
 	"github.com/metis-devops/metis-bridge-rebate/internal/utils"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 )
 
 type DataSync struct {
-	EtherClient        *ethclient.Client
+	EtherClient        L1Client
 	Bridge             *goabi.L1StandardBridge
 	Repositroy         repository.Metis
 	ConfirmationNumber uint64
+	ConfirmationMode   ConfirmationMode
 	RangeSync          uint64
 	DripHeight         uint64
+	SyncWorkers        int
 
-	height uint64
+	height         uint64
+	span           *rangeSpan
+	defaultChainId uint64
 }
 
 func (s *DataSync) Prefight(basectx context.Context, startFrom uint64) (err error) {
 	newctx, cancel := context.WithTimeout(basectx, time.Second*5)
 	defer cancel()
 	s.height, err = s.Repositroy.InitHeight(newctx, startFrom)
+	if err != nil {
+		return
+	}
+
+	span, err := s.Repositroy.GetRangeSpan(newctx)
+	if err != nil {
+		return
+	}
+	s.span = newRangeSpan(span, s.RangeSync)
+	logrus.Infof("Range sync span is %d", s.span.get())
+
+	if err = s.initDefaultChainId(newctx); err != nil {
+		return
+	}
+	logrus.Infof("Default destination chain id is %d", s.defaultChainId)
+
+	// make sure the provider supports the block tag
+	target, err := s.getTargetHeight(basectx)
+	if err != nil {
+		return
+	}
+	logrus.Infof("Confirmation mode is %s, the current target height is %d", s.ConfirmationMode, target)
 	return
 }
 
@@ -45,129 +73,360 @@ func (s *DataSync) Run(basectx context.Context) {
 }
 
 func (s *DataSync) tryToSync(basectx context.Context) error {
-	latestBlock, err := func() (uint64, error) {
-		newctx, cancle := context.WithTimeout(basectx, time.Second*10)
-		defer cancle()
-		block, err := s.EtherClient.BlockNumber(newctx)
-		if err != nil {
-			return 0, err
-		}
-		if block > s.ConfirmationNumber {
-			return block - s.ConfirmationNumber, nil
-		}
-		return block, nil
-	}()
+	if err := s.checkReorg(basectx); err != nil {
+		return err
+	}
+
+	targetHeight, err := s.getTargetHeight(basectx)
 	if err != nil {
 		return err
 	}
+	return s.syncTo(basectx, targetHeight)
+}
+
+// syncTo fetches the ranges concurrently with a bounded worker pool,
+// and commits them strictly in height order so the height cursor never skips a gap.
+func (s *DataSync) syncTo(basectx context.Context, targetHeight uint64) error {
+	ctx, cancel := context.WithCancel(basectx)
+	defer cancel()
+
+	workers := max(s.SyncWorkers, 1)
+	results := make(chan chan *syncedRange, workers)
+	go func(startHeight uint64) {
+		defer close(results)
+		sem := make(chan struct{}, workers)
+		for startHeight < targetHeight {
+			endHeight := min(startHeight+s.span.get(), targetHeight)
+			select {
+			case <-ctx.Done():
+				return
+			case sem <- struct{}{}:
+			}
 
-	for targetHeight := latestBlock; s.height < targetHeight; {
-		startHeight, endHeight := s.height, s.height+s.RangeSync
-		if endHeight > targetHeight {
-			endHeight = targetHeight
+			result := make(chan *syncedRange, 1)
+			go func(startHeight, endHeight uint64) {
+				defer func() { <-sem }()
+				result <- s.fetchRange(ctx, startHeight, endHeight)
+			}(startHeight, endHeight)
+
+			select {
+			case <-ctx.Done():
+				return
+			case results <- result:
+			}
+			startHeight = endHeight + 1
+		}
+	}(s.height)
+
+	for result := range results {
+		var synced *syncedRange
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case synced = <-result:
 		}
-		if err := s.syncWithRange(basectx, startHeight, endHeight); err != nil {
+		if err := s.saveRange(ctx, synced); err != nil {
 			return err
 		}
-		s.height = endHeight + 1
+		s.height = synced.end + 1
 	}
-	return nil
+	return ctx.Err()
 }
 
-func (s *DataSync) syncWithRange(basectx context.Context, startHeight, endHeight uint64) error {
+func (s *DataSync) formatERC20DepositEvent(event *goabi.L1StandardBridgeERC20DepositInitiated) (*repository.Deposit, error) {
+	var status = repository.DepositStatusUnprocessed
+	l2token := strings.ToLower(event.L2Token.Hex())
+	if l2token == utils.MetisL2Address {
+		status = repository.DepositStatusIgnore
+	}
+
+	if s.DripHeight > event.Raw.BlockNumber {
+		status = repository.DepositStatusIgnore
+		logrus.Infof("Tx %s is not ready to have a drip[DripHeight %v > TxHeight %v]", event.Raw.TxHash, s.DripHeight, event.Raw.BlockNumber)
+	}
+
+	return &repository.Deposit{
+		Height:    event.Raw.BlockNumber,
+		Blockhash: event.Raw.BlockHash.Hex(),
+		BlockTime: time.Unix(int64(event.Raw.BlockTimestamp), 0).UTC(),
+		Txid:      event.Raw.TxHash.Hex(),
+		LogIndex:  event.Raw.Index,
+		L1Token:   strings.ToLower(event.L1Token.Hex()),
+		L2Token:   l2token,
+		From:      strings.ToLower(event.From.Hex()),
+		To:        strings.ToLower(event.To.Hex()),
+		Amount:    bigint.FromBigInt(event.Amount),
+		Status:    status,
+	}, nil
+}
+
+func (s *DataSync) formatETHDepositEvent(event *goabi.L1StandardBridgeETHDepositInitiated) (*repository.Deposit, error) {
+	var chainId = s.defaultChainId
+	if event.ChainId != nil && event.ChainId.Sign() > 0 {
+		chainId = event.ChainId.Uint64()
+	}
+
+	var status = repository.DepositStatusUnprocessed
+	if s.DripHeight > event.Raw.BlockNumber {
+		status = repository.DepositStatusIgnore
+		logrus.Infof("Tx %s is not ready to have a drip[DripHeight %v > TxHeight %v]", event.Raw.TxHash, s.DripHeight, event.Raw.BlockNumber)
+	}
+
+	return &repository.Deposit{
+		Height:    event.Raw.BlockNumber,
+		Blockhash: event.Raw.BlockHash.Hex(),
+		BlockTime: time.Unix(int64(event.Raw.BlockTimestamp), 0).UTC(),
+		Txid:      event.Raw.TxHash.Hex(),
+		LogIndex:  event.Raw.Index,
+		ChainId:   chainId,
+		L1Token:   strings.ToLower(utils.EtherL1Address),
+		L2Token:   strings.ToLower(utils.EtherL2Address),
+		From:      strings.ToLower(event.From.Hex()),
+		To:        strings.ToLower(event.To.Hex()),
+		Amount:    bigint.FromBigInt(event.Amount),
+		Status:    status,
+	}, nil
+}
+
+// rangeEvents is the bridge events in a range
+type rangeEvents struct {
+	deposits    []*repository.Deposit
+	withdrawals []*repository.Withdrawal
+}
+
+func (e *rangeEvents) merge(other *rangeEvents) *rangeEvents {
+	e.deposits = append(e.deposits, other.deposits...)
+	e.withdrawals = append(e.withdrawals, other.withdrawals...)
+	return e
+}
+
+type syncedRange struct {
+	start  uint64
+	end    uint64
+	tail   *types.Header
+	events *rangeEvents
+	err    error
+}
+
+// fetchRange fetches the tail header and the bridge events in the range concurrently
+func (s *DataSync) fetchRange(basectx context.Context, startHeight, endHeight uint64) *syncedRange {
 	logrus.Infof("Syncing from %d to %d", startHeight, endHeight)
 
 	newctx, cancel := context.WithTimeout(basectx, time.Minute*10)
 	defer cancel()
 
-	formatERC20DepositEvent := func(event *goabi.L1StandardBridgeERC20DepositInitiated) (*repository.Deposit, error) {
-		var status = repository.DepositStatusUnprocessed
-		l2token := strings.ToLower(event.L2Token.Hex())
-		if l2token == utils.MetisL2Address {
-			status = repository.DepositStatusIgnore
-		}
+	var (
+		result    = &syncedRange{start: startHeight, end: endHeight}
+		eg, egctx = errgroup.WithContext(newctx)
+	)
 
-		if s.DripHeight > event.Raw.BlockNumber {
-			status = repository.DepositStatusIgnore
-			logrus.Infof("Tx %s is not ready to have a drip[DripHeight %v > TxHeight %v]", event.Raw.TxHash, s.DripHeight, event.Raw.BlockNumber)
+	eg.Go(func() error {
+		header, err := s.EtherClient.HeaderByNumber(egctx, new(big.Int).SetUint64(endHeight))
+		if err != nil {
+			return fmt.Errorf("fetchRange: get tail header: %w", err)
 		}
+		result.tail = header
+		return nil
+	})
+
+	eg.Go(func() (err error) {
+		result.events, err = s.fetchEvents(egctx, s.span, startHeight, endHeight)
+		return err
+	})
+
+	result.err = eg.Wait()
+	return result
+}
 
-		return &repository.Deposit{
-			Height:  event.Raw.BlockNumber,
-			Txid:    event.Raw.TxHash.Hex(),
-			L1Token: strings.ToLower(event.L1Token.Hex()),
-			L2Token: l2token,
-			From:    strings.ToLower(event.From.Hex()),
-			To:      strings.ToLower(event.To.Hex()),
-			Amount:  bigint.FromBigInt(event.Amount),
-			Status:  status,
-		}, nil
-	}
-
-	formatEthDespoitEvent := func(event *goabi.L1StandardBridgeETHDepositInitiated) (*repository.Deposit, error) {
-		var status = repository.DepositStatusUnprocessed
-		if s.DripHeight > event.Raw.BlockNumber {
-			status = repository.DepositStatusIgnore
-			logrus.Infof("Tx %s is not ready to have a drip[DripHeight %v > TxHeight %v]", event.Raw.TxHash, s.DripHeight, event.Raw.BlockNumber)
+// fetchEvents fetches the bridge events in the range, the range is bisected if the rpc provider rejects it,
+// the learned span is persisted only if it's the span of the live sync.
+func (s *DataSync) fetchEvents(ctx context.Context, learner *rangeSpan, startHeight, endHeight uint64) (*rangeEvents, error) {
+	span := endHeight - startHeight
+	events, err := s.fetchRangeEvents(ctx, startHeight, endHeight)
+	if err == nil {
+		if span, changed := learner.succeed(span); changed {
+			logrus.Infof("Growing the range span to %d", span)
+			if learner == s.span {
+				s.saveSpan(ctx, span)
+			}
 		}
+		return events, nil
+	}
 
-		return &repository.Deposit{
-			Height:  event.Raw.BlockNumber,
-			Txid:    event.Raw.TxHash.Hex(),
-			L1Token: strings.ToLower(utils.EtherL1Address),
-			L2Token: strings.ToLower(utils.EtherL2Address),
-			From:    strings.ToLower(event.From.Hex()),
-			To:      strings.ToLower(event.To.Hex()),
-			Amount:  bigint.FromBigInt(event.Amount),
-			Status:  status,
-		}, nil
+	if startHeight == endHeight || !isRangeTooLargeError(err) {
+		return nil, err
 	}
 
-	header, err := s.EtherClient.HeaderByNumber(newctx, big.NewInt(int64(endHeight)))
+	if span, changed := learner.shrink(span); changed {
+		logrus.Warnf("Range %d-%d is rejected, shrinking the range span to %d: %s", startHeight, endHeight, span, err)
+		if learner == s.span {
+			s.saveSpan(ctx, span)
+		}
+	}
+
+	middle := startHeight + span/2
+	left, err := s.fetchEvents(ctx, learner, startHeight, middle)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.fetchEvents(ctx, learner, middle+1, endHeight)
 	if err != nil {
-		return fmt.Errorf("syncWithRange: get tail header: %w", err)
+		return nil, err
+	}
+	return left.merge(right), nil
+}
+
+// fetchRangeEvents fetches all kinds of the bridge events concurrently
+func (s *DataSync) fetchRangeEvents(ctx context.Context, startHeight, endHeight uint64) (*rangeEvents, error) {
+	var (
+		erc20Deposits, etherDeposits       []*repository.Deposit
+		erc20Withdrawals, etherWithdrawals []*repository.Withdrawal
+		chainIDs                           erc20ChainIDs
+		eg, egctx                          = errgroup.WithContext(ctx)
+		filterOption                       = &bind.FilterOpts{Context: egctx, Start: startHeight, End: &endHeight}
+	)
+
+	eg.Go(func() (err error) {
+		erc20Deposits, err = s.filterERC20Deposits(filterOption)
+		return err
+	})
+	eg.Go(func() (err error) {
+		etherDeposits, err = s.filterETHDeposits(filterOption)
+		return err
+	})
+	eg.Go(func() (err error) {
+		chainIDs, err = s.filterERC20ChainIDs(filterOption)
+		return err
+	})
+	eg.Go(func() (err error) {
+		erc20Withdrawals, err = s.filterERC20Withdrawals(filterOption)
+		return err
+	})
+	eg.Go(func() (err error) {
+		etherWithdrawals, err = s.filterETHWithdrawals(filterOption)
+		return err
+	})
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
 	}
-	var tail = &repository.Height{Number: endHeight, Blockhash: header.Hash().String()}
 
-	filterOption := &bind.FilterOpts{Context: newctx, Start: startHeight, End: &endHeight}
-	erc20Iter, err := s.Bridge.FilterERC20DepositInitiated(filterOption, nil, nil, nil)
+	chainIDs.assign(erc20Deposits, s.defaultChainId)
+	events := &rangeEvents{
+		deposits:    append(erc20Deposits, etherDeposits...),
+		withdrawals: append(erc20Withdrawals, etherWithdrawals...),
+	}
+	if err := s.fillBlockTime(ctx, events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (s *DataSync) filterERC20Deposits(filterOption *bind.FilterOpts) ([]*repository.Deposit, error) {
+	iter, err := s.Bridge.FilterERC20DepositInitiated(filterOption, nil, nil, nil)
 	if err != nil {
-		return fmt.Errorf("syncWithRange: filter erc20 deposit event: %w", err)
+		return nil, fmt.Errorf("filterERC20Deposits: %w", err)
 	}
-	defer erc20Iter.Close()
+	defer iter.Close()
 
 	var deposits []*repository.Deposit
-	for erc20Iter.Next() {
-		dpt, err := formatERC20DepositEvent(erc20Iter.Event)
+	for iter.Next() {
+		dpt, err := s.formatERC20DepositEvent(iter.Event)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		deposits = append(deposits, dpt)
 	}
-	if err := erc20Iter.Error(); err != nil {
-		return fmt.Errorf("syncWithRange: filter erc20 deposit event: %w", err)
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("filterERC20Deposits: %w", err)
 	}
+	return deposits, nil
+}
 
-	etherInter, err := s.Bridge.FilterETHDepositInitiated(filterOption, nil, nil)
+func (s *DataSync) filterETHDeposits(filterOption *bind.FilterOpts) ([]*repository.Deposit, error) {
+	iter, err := s.Bridge.FilterETHDepositInitiated(filterOption, nil, nil)
 	if err != nil {
-		return fmt.Errorf("syncWithRange: filter ether deposit event: %w", err)
+		return nil, fmt.Errorf("filterETHDeposits: %w", err)
 	}
-	defer etherInter.Close()
-	for etherInter.Next() {
-		dpt, err := formatEthDespoitEvent(etherInter.Event)
+	defer iter.Close()
+
+	var deposits []*repository.Deposit
+	for iter.Next() {
+		dpt, err := s.formatETHDepositEvent(iter.Event)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		deposits = append(deposits, dpt)
 	}
-	if err := etherInter.Error(); err != nil {
-		return fmt.Errorf("syncWithRange: filter ether deposit event: %w", err)
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("filterETHDeposits: %w", err)
+	}
+	return deposits, nil
+}
+
+// fillBlockTime fills the block time for the events if the rpc provider doesn't return the block timestamp with logs
+func (s *DataSync) fillBlockTime(ctx context.Context, events *rangeEvents) error {
+	cache := make(map[string]time.Time)
+	getBlockTime := func(blockhash string) (time.Time, error) {
+		if blockTime, ok := cache[blockhash]; ok {
+			return blockTime, nil
+		}
+		header, err := s.EtherClient.HeaderByHash(ctx, common.HexToHash(blockhash))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("fillBlockTime: get header %s: %w", blockhash, err)
+		}
+		blockTime := time.Unix(int64(header.Time), 0).UTC()
+		cache[blockhash] = blockTime
+		return blockTime, nil
+	}
+
+	for _, dpt := range events.deposits {
+		if dpt.BlockTime.Unix() > 0 {
+			continue
+		}
+		blockTime, err := getBlockTime(dpt.Blockhash)
+		if err != nil {
+			return err
+		}
+		dpt.BlockTime = blockTime
 	}
 
-	if err := s.Repositroy.SaveSyncedData(newctx, deposits, tail); err != nil {
-		return fmt.Errorf("syncWithRange: %w", err)
+	for _, wd := range events.withdrawals {
+		if wd.BlockTime.Unix() > 0 {
+			continue
+		}
+		blockTime, err := getBlockTime(wd.Blockhash)
+		if err != nil {
+			return err
+		}
+		wd.BlockTime = blockTime
+	}
+	return nil
+}
+
+// saveSpan persists the learned range span so restarts don't start from the oversized default
+func (s *DataSync) saveSpan(basectx context.Context, span uint64) {
+	newctx, cancel := context.WithTimeout(context.WithoutCancel(basectx), time.Second*5)
+	defer cancel()
+	if err := s.Repositroy.SaveRangeSpan(newctx, span); err != nil {
+		logrus.Errorf("save range span: %s", err)
+	}
+}
+
+func (s *DataSync) saveRange(basectx context.Context, synced *syncedRange) error {
+	if synced.err != nil {
+		return synced.err
+	}
+
+	newctx, cancel := context.WithTimeout(basectx, time.Minute)
+	defer cancel()
+
+	var tail = &repository.Height{Number: synced.end, Blockhash: synced.tail.Hash().String()}
+	if err := s.Repositroy.SaveSyncedData(newctx, synced.events.deposits, synced.events.withdrawals, tail); err != nil {
+		return fmt.Errorf("saveRange: %w", err)
 	}
 
-	logrus.Infof("Done: NewDeposits %d BlockTime %s", len(deposits), time.Unix(int64(header.Time), 0))
+	logrus.Infof("Done: From %d To %d NewDeposits %d NewWithdrawals %d BlockTime %s",
+		synced.start, synced.end, len(synced.events.deposits), len(synced.events.withdrawals), time.Unix(int64(synced.tail.Time), 0))
 	return nil
 }
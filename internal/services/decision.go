@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/metis-devops/metis-bridge-rebate/internal/services/policy"
+)
+
+// ReasonCode is the stable code of a drip decision, it's recorded in the decisions table
+type ReasonCode string
+
+const (
+	ReasonEligible         ReasonCode = "eligible"
+	ReasonNoPolicy         ReasonCode = "no_policy"
+	ReasonTokenUnsupported ReasonCode = "token_unsupported"
+	ReasonDuplicateInLoop  ReasonCode = "duplicate_in_loop"
+	ReasonSybilRecipients  ReasonCode = "sybil_recipients"
+	ReasonSybilScore       ReasonCode = "sybil_score"
+	ReasonNoTokenInfo      ReasonCode = "no_token_info"
+	ReasonAmountTooLow     ReasonCode = "amount_too_low"
+	ReasonNotFirstDrip     ReasonCode = "not_first_drip"
+	ReasonNonceNotZero     ReasonCode = "nonce_not_zero"
+	ReasonRateLimited      ReasonCode = "rate_limited"
+	ReasonHasGas           ReasonCode = "has_gas"
+	ReasonNotEOA           ReasonCode = "not_eoa"
+	ReasonBudgetExhausted  ReasonCode = "budget_exhausted"
+)
+
+// the checks of shouldTransfer, every rejection reason belongs to one of them
+const (
+	checkPolicy    = "policy"
+	checkToken     = "token"
+	checkLoop      = "loop"
+	checkSybil     = "sybil"
+	checkMinUSD    = "min_usd"
+	checkFirstDrip = "first_drip"
+	checkFresh     = "fresh_address"
+	checkRateLimit = "rate_limit"
+	checkNoGas     = "no_gas"
+	checkEOA       = "eoa"
+	checkBudget    = "budget"
+)
+
+var reasonChecks = map[ReasonCode]string{
+	ReasonNoPolicy:         checkPolicy,
+	ReasonTokenUnsupported: checkToken,
+	ReasonDuplicateInLoop:  checkLoop,
+	ReasonSybilRecipients:  checkSybil,
+	ReasonSybilScore:       checkSybil,
+	ReasonNoTokenInfo:      checkMinUSD,
+	ReasonAmountTooLow:     checkMinUSD,
+	ReasonNotFirstDrip:     checkFirstDrip,
+	ReasonNonceNotZero:     checkFresh,
+	ReasonRateLimited:      checkRateLimit,
+	ReasonHasGas:           checkNoGas,
+	ReasonNotEOA:           checkEOA,
+	ReasonBudgetExhausted:  checkBudget,
+}
+
+type checkOutcome struct {
+	Check  string `json:"check"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// decisionRecorder collects the check outcomes and the prices used for a deposit, its methods do nothing on nil
+type decisionRecorder struct {
+	checks     []checkOutcome
+	tokenPrice float64
+	depositUSD float64
+}
+
+func (r *decisionRecorder) pass(check, detail string) {
+	if r != nil {
+		r.checks = append(r.checks, checkOutcome{Check: check, Passed: true, Detail: detail})
+	}
+}
+
+func (r *decisionRecorder) fail(code ReasonCode, detail string) {
+	if r != nil {
+		r.checks = append(r.checks, checkOutcome{Check: reasonChecks[code], Detail: detail})
+	}
+}
+
+func (r *decisionRecorder) setPrice(tokenPrice, depositUSD float64) {
+	if r != nil {
+		r.tokenPrice, r.depositUSD = tokenPrice, depositUSD
+	}
+}
+
+// saveDecision records the decision of the deposit with the collected checks, metis and usd are the drip values.
+// A skip is recorded only if it differs from the last decision of the deposit, since a skipped deposit is decided again in every loop.
+func (s *Faucet) saveDecision(ctx context.Context, rec *decisionRecorder, policyName string, item *repository.Deposit,
+	decision repository.DripDecision, code ReasonCode, message string, metis, usd float64) error {
+	record, err := s.newDecision(rec, policyName, item, decision, code, message, metis, usd)
+	if err != nil {
+		return err
+	}
+	if decision == repository.DecisionSkip {
+		_, err := s.Repositroy.SaveDecisionIfChanged(ctx, record)
+		return err
+	}
+	return s.Repositroy.SaveDecision(ctx, record)
+}
+
+// newDecision builds the decision record of the deposit, the eligible one is saved with the drip
+func (s *Faucet) newDecision(rec *decisionRecorder, policyName string, item *repository.Deposit,
+	decision repository.DripDecision, code ReasonCode, message string, metis, usd float64) (*repository.Decision, error) {
+	if rec == nil {
+		rec = new(decisionRecorder)
+	}
+	checks, err := json.Marshal(rec.checks)
+	if err != nil {
+		return nil, fmt.Errorf("newDecision: %w", err)
+	}
+	if string(checks) == "null" {
+		checks = []byte("[]")
+	}
+	return &repository.Decision{
+		Pid:        item.Id,
+		ChainId:    s.L2ChainId,
+		Txid:       item.Txid,
+		From:       item.From,
+		To:         item.To,
+		Policy:     policyName,
+		Decision:   decision,
+		Reason:     string(code),
+		Message:    truncate(message, 255),
+		Checks:     checks,
+		TokenPrice: rec.tokenPrice,
+		DepositUSD: rec.depositUSD,
+		DripAmount: metis,
+		DripUSD:    usd,
+	}, nil
+}
+
+func policyName(pc *policy.Drip) string {
+	if pc == nil {
+		return ""
+	}
+	return pc.Name
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecisionRecorder(t *testing.T) {
+	var nilRecorder *decisionRecorder
+	nilRecorder.pass(checkPolicy, "Default")
+	nilRecorder.fail(ReasonNotEOA, "not EOA")
+
+	rec := new(decisionRecorder)
+	rec.pass(checkPolicy, "Default")
+	rec.setPrice(2, 400)
+	rec.fail(ReasonNotEOA, "not EOA")
+
+	data, err := json.Marshal(rec.checks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"check":"policy","passed":true,"detail":"Default"},{"check":"eoa","passed":false,"detail":"not EOA"}]`
+	if string(data) != want {
+		t.Errorf("checks = %s, want %s", data, want)
+	}
+	if rec.tokenPrice != 2 || rec.depositUSD != 400 {
+		t.Errorf("prices = %f %f, want 2 400", rec.tokenPrice, rec.depositUSD)
+	}
+
+	for _, code := range []ReasonCode{
+		ReasonNoPolicy, ReasonTokenUnsupported, ReasonDuplicateInLoop, ReasonSybilRecipients, ReasonSybilScore,
+		ReasonNoTokenInfo, ReasonAmountTooLow, ReasonNotFirstDrip, ReasonNonceNotZero, ReasonRateLimited,
+		ReasonHasGas, ReasonNotEOA, ReasonBudgetExhausted,
+	} {
+		if reasonChecks[code] == "" {
+			t.Errorf("reason %s has no check", code)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"time"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+const (
+	// the delay of the first retry, it doubles on every later attempt
+	deferBackoff = time.Minute * 5
+	// the max delay between the retries
+	maxDeferBackoff = time.Hour * 6
+)
+
+// Transient reports whether the rejection may be gone later, e.g. the token price or the policy is not ready yet,
+// the deposits rejected for the transient reasons are deferred rather than ignored.
+func (c ReasonCode) Transient() bool {
+	switch c {
+	case ReasonNoPolicy, ReasonTokenUnsupported, ReasonNoTokenInfo:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e ErrorNoNeedToTransfer) Transient() bool {
+	return e.code.Transient()
+}
+
+// deferDelay returns the backoff before the next retry of a deposit deferred for the given times
+func deferDelay(attempts uint) time.Duration {
+	delay := deferBackoff
+	for i := uint(0); i < attempts && delay < maxDeferBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDeferBackoff)
+}
+
+// deferUntil returns the next retry time of the rejected deposit,
+// it returns false if the rejection is permanent, or the deposit has expired by the next retry.
+func (s *Faucet) deferUntil(item *repository.Deposit, rejection ErrorNoNeedToTransfer, now time.Time) (time.Time, bool) {
+	if !rejection.Transient() || s.DeferExpiry <= 0 {
+		return time.Time{}, false
+	}
+	next := now.Add(deferDelay(item.Attempts))
+	if next.After(item.BlockTime.Add(s.DeferExpiry)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+func TestDeferDelay(t *testing.T) {
+	tests := []struct {
+		attempts uint
+		want     time.Duration
+	}{
+		{0, time.Minute * 5},
+		{1, time.Minute * 10},
+		{3, time.Minute * 40},
+		{7, time.Hour * 6},
+		{100, time.Hour * 6},
+	}
+	for _, tt := range tests {
+		if got := deferDelay(tt.attempts); got != tt.want {
+			t.Errorf("deferDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeferUntil(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	s := &Faucet{DeferExpiry: time.Hour * 24}
+	transient := ErrorNoNeedToTransfer{code: ReasonNoTokenInfo}
+
+	tests := []struct {
+		name      string
+		rejection ErrorNoNeedToTransfer
+		blockTime time.Time
+		attempts  uint
+		want      time.Time
+		wantOk    bool
+	}{
+		{"permanent", ErrorNoNeedToTransfer{code: ReasonNotEOA}, now, 0, time.Time{}, false},
+		{"first deferral", transient, now, 0, now.Add(time.Minute * 5), true},
+		{"backoff", transient, now.Add(-time.Hour), 2, now.Add(time.Minute * 20), true},
+		{"expired", transient, now.Add(-time.Hour * 23), 5, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &repository.Deposit{BlockTime: tt.blockTime, Attempts: tt.attempts}
+			got, ok := s.deferUntil(item, tt.rejection, now)
+			if ok != tt.wantOk || !got.Equal(tt.want) {
+				t.Errorf("deferUntil() = %v %v, want %v %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+
+	s.DeferExpiry = 0
+	if _, ok := s.deferUntil(&repository.Deposit{BlockTime: now}, transient, now); ok {
+		t.Errorf("deferUntil() defers with the deferral disabled")
+	}
+}
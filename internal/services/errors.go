@@ -1,9 +1,21 @@
 package services
 
 type ErrorNoNeedToTransfer struct {
-	msg string
+	code ReasonCode
+	msg  string
 }
 
 func (e ErrorNoNeedToTransfer) Error() string {
 	return e.msg
 }
+
+// ErrorBudgetExhausted means the drip exceeds a budget, the deposit is skipped until the window rolls over
+type ErrorBudgetExhausted struct {
+	msg string
+	// the global budget pauses all the drips rather than the ones of a policy
+	global bool
+}
+
+func (e ErrorBudgetExhausted) Error() string {
+	return e.msg
+}
@@ -2,17 +2,18 @@ package services
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/islishude/bigint"
 	"github.com/metis-devops/metis-bridge-rebate/internal/goabi"
 	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
 	"github.com/metis-devops/metis-bridge-rebate/internal/services/policy"
@@ -21,21 +22,54 @@ import (
 )
 
 type Faucet struct {
-	EthClient       *ethclient.Client
+	EthClient       L1Client
 	MetisClient     *ethclient.Client
 	Repositroy      repository.Metis
 	Uniswap         utils.Uniswaper
 	MetisL1Contract string
-
-	Prvkey       *ecdsa.PrivateKey
-	Account      common.Address
-	Eip155Signer types.Signer
-	nonce        uint64
+	L2ChainId       uint64
+	// the layer2 height to start scanning the relays, it starts from the relay window of the oldest pending deposit if it's zero
+	RelayStartBlock uint64
+	// the unprocessed deposits not relayed within it are ignored, 0 disables it
+	RelayTimeout time.Duration
+	relayHeight  uint64
+	l2Bridge     *goabi.L2StandardBridgeFilterer
+
+	// the hot wallets sending the drips in turn
+	Wallets   []*Wallet
+	Signer    types.Signer
+	walletIdx int
+
+	FeeMode          FeeMode
+	TipMultiplier    float64
+	FeeCapMultiplier float64
+	dynamicFee       bool
+	// the pending drips older than it are replaced with bumped fees, 0 disables the replacement
+	StuckAge time.Duration
+	// the max fee cap or gas price in wei the replacements are bumped to, nil or 0 disables the cap
+	MaxFee *big.Int
+
+	// the disperse contract to send the drips of a loop in batches, the batch mode is disabled if it's zero
+	DisperseContract common.Address
+	BatchSize        int
+	// the abi packs the disperse calls, and the binding decodes the disperse events
+	disperseABI *abi.ABI
+	disperse    *goabi.DisperseFilterer
 
 	DefaultDrip     *big.Int
 	MaxDripUSD      float64
 	ReservedBalance float64
 	DripPolicies    []*policy.Drip
+	// the global budgets pause all the drips once any of them is exhausted
+	Budgets []policy.Budget
+	// trace the layer1 funders of the deposit senders for the sybil scoring, it needs an archive node
+	TraceFunder bool
+	// the deposits rejected for transient reasons are deferred until it passes since the deposit, 0 disables the deferral
+	DeferExpiry time.Duration
+
+	// the shadow run name, the faucet only records its decisions in the shadow table if it's not empty,
+	// nothing is signed or sent and the deposit status is never changed.
+	DryRun string
 }
 
 func (s *Faucet) Initial(basectx context.Context) (err error) {
@@ -51,36 +85,101 @@ func (s *Faucet) Initial(basectx context.Context) (err error) {
 		return errors.New("no default drip policy")
 	}
 
+	if len(s.Wallets) == 0 && s.DryRun == "" {
+		return errors.New("no wallet")
+	}
+
 	if s.DefaultDrip == nil || s.DefaultDrip.Sign() < 1 {
 		s.DefaultDrip = big.NewInt(1e16)
 	}
 
-	newctx, cancel := context.WithTimeout(basectx, time.Second)
+	s.l2Bridge, err = goabi.NewL2StandardBridgeFilterer(common.HexToAddress(utils.MetisL2BridgeAddress), s.MetisClient)
+	if err != nil {
+		return err
+	}
+
+	if s.relayHeight, err = s.initRelayHeight(basectx); err != nil {
+		return err
+	}
+
+	newctx, cancel := context.WithTimeout(basectx, time.Second*5)
 	defer cancel()
-	s.nonce, err = s.MetisClient.NonceAt(newctx, s.Account, nil)
-	return
+
+	// the pending batches are checked even if the batch mode is disabled later
+	if s.disperseABI, err = goabi.DisperseMetaData.GetAbi(); err != nil {
+		return err
+	}
+	if s.disperse, err = goabi.NewDisperseFilterer(s.DisperseContract, s.MetisClient); err != nil {
+		return err
+	}
+	if err := s.detectFeeMode(newctx); err != nil {
+		return err
+	}
+	if s.DryRun != "" {
+		return nil
+	}
+	if err := s.fillNonceLegacy(newctx); err != nil {
+		return err
+	}
+	for _, w := range s.Wallets {
+		if err := s.reconcileNonce(newctx, w); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *Faucet) SendDrips(basectx context.Context) {
 	newctx, cancel := context.WithTimeout(basectx, time.Minute*5)
 	defer cancel()
-	if err := s.checkBalance(basectx); err != nil {
+	if s.DryRun != "" {
+		s.shadowDrips(newctx)
+		return
+	}
+	wallets, err := s.getHealthyWallets(newctx)
+	if err != nil {
 		logrus.Errorf("check balance: %s", err)
 		return
 	}
+	// the wallets failed to reconcile their nonces are left out of this loop
+	var reconciled []*Wallet
+	for _, w := range wallets {
+		if err := s.reconcileNonce(newctx, w); err != nil {
+			logrus.Errorf("reconcile nonce of wallet %s: %s", w.Account, err)
+			continue
+		}
+		reconciled = append(reconciled, w)
+	}
+	if len(reconciled) == 0 {
+		logrus.Errorf("no wallet is available to send drips")
+		return
+	}
+	wallets = reconciled
+	if err := s.SyncRelays(newctx); err != nil {
+		logrus.Errorf("sync relays: %s", err)
+		return
+	}
 	tokens, err := utils.GetBridgeTokens(newctx)
 	if err != nil {
 		logrus.Errorf("Get supported tokens: %s", err)
 		return
 	}
-	if err := s.tryToSendDrip(newctx, tokens); err != nil {
+	if err := s.tryToSendDrip(newctx, wallets, tokens); err != nil {
 		logrus.Errorf("failed to transfer drips: %s", err)
 	}
 }
 
-func (s *Faucet) tryToSendDrip(ctx context.Context, bridgeTokens map[string]string) error {
+func (s *Faucet) tryToSendDrip(ctx context.Context, wallets []*Wallet, bridgeTokens map[string]string) error {
 	recset := make(map[string]bool)
-	for item := range s.Repositroy.GetDepositTxStream(ctx, repository.DepositStatusUnprocessed) {
+	batch := new(dripBatch)
+	budgets := s.newBudgetTracker(time.Now())
+	// the usd values are always recorded, so the budgets configured later see the full spending
+	tokenInfo, err := s.Uniswap.GetToken(ctx, s.MetisL1Contract)
+	if err != nil {
+		return fmt.Errorf("get metis price: %w", err)
+	}
+	metisUSD := tokenInfo.ValueInUSD
+	for item := range s.Repositroy.GetDepositTxStream(ctx, s.L2ChainId, repository.DepositStatusUnprocessed) {
 		if item.Error != nil {
 			return item.Error
 		}
@@ -88,17 +187,35 @@ func (s *Faucet) tryToSendDrip(ctx context.Context, bridgeTokens map[string]stri
 		logrus.Infof("Try to send drip: Txid %s Receiver %s", item.Data.Txid, item.Data.To)
 		var policy *policy.Drip
 		for _, p := range s.DripPolicies {
-			if p.Match(item.Data.CreatedAt, item.Data.L1Token) {
+			if p.Match(item.Data.BlockTime, item.Data.L1Token) {
 				policy = p
 			}
 		}
 
 		var shouldTransfer = true
-		err := s.shouldTransfer(ctx, policy, item.Data, recset, bridgeTokens)
+		rec := new(decisionRecorder)
+		err := s.shouldTransfer(ctx, policy, item.Data, recset, bridgeTokens, rec)
 		if err != nil {
 			if v, ok := err.(ErrorNoNeedToTransfer); ok {
-				logrus.Infof("Don't need to give a drip: %s", v.msg)
+				if next, ok := s.deferUntil(item.Data, v, time.Now()); ok {
+					logrus.Infof("Defer the drip until %s: %s", next.Format(time.RFC3339), v.msg)
+					if err := s.saveDecision(ctx, rec, policyName(policy), item.Data, repository.DecisionDefer, v.code, v.msg, 0, 0); err != nil {
+						return err
+					}
+					if err := s.Repositroy.DeferDeposit(ctx, item.Data.Id, next); err != nil {
+						return err
+					}
+					continue
+				}
+				msg := v.msg
+				if v.Transient() && item.Data.Attempts > 0 {
+					msg = fmt.Sprintf("%s, expired after %d deferrals", msg, item.Data.Attempts)
+				}
+				logrus.Infof("Don't need to give a drip: %s", msg)
 				shouldTransfer = false
+				if err := s.saveDecision(ctx, rec, policyName(policy), item.Data, repository.DecisionIgnore, v.code, msg, 0, 0); err != nil {
+					return err
+				}
 			} else {
 				return err
 			}
@@ -106,13 +223,62 @@ func (s *Faucet) tryToSendDrip(ctx context.Context, bridgeTokens map[string]stri
 
 		var drip *repository.Drip
 		var tx *types.Transaction
+		var w *Wallet
 		if shouldTransfer {
 			dripAmount, err := s.calMetisDrip(ctx, policy, item.Data.Txid)
 			if err != nil {
 				return err
 			}
 
-			tx, err = s.makeDripTx(ctx, item.Data.To, dripAmount)
+			metis := utils.ToEther(dripAmount)
+			usd := metis * metisUSD
+			if err := s.checkBudgets(ctx, budgets, policy, metis, usd); err != nil {
+				v, ok := err.(ErrorBudgetExhausted)
+				if !ok {
+					return err
+				}
+				// the deposit stays unprocessed and is tried again after the window rolls over
+				logrus.Warnf("Skip the drip of %s: %s", item.Data.Txid, v.msg)
+				rec.fail(ReasonBudgetExhausted, v.msg)
+				if err := s.saveDecision(ctx, rec, policy.Name, item.Data, repository.DecisionSkip, ReasonBudgetExhausted, v.msg, metis, usd); err != nil {
+					return err
+				}
+				if v.global {
+					break
+				}
+				continue
+			}
+			s.spendBudgets(budgets, policy, metis, usd)
+			rec.pass(checkBudget, "")
+			// the decision is saved with the drip, so it's not recorded if the drip is not saved
+			decision, err := s.newDecision(rec, policy.Name, item.Data, repository.DecisionDrip, ReasonEligible, "", metis, usd)
+			if err != nil {
+				return err
+			}
+
+			drip = &repository.Drip{
+				Pid:      item.Data.Id,
+				To:       item.Data.To,
+				Amount:   metis,
+				Policy:   policy.Name,
+				USD:      usd,
+				Limits:   s.dripLimits(policy, item.Data),
+				Decision: decision,
+			}
+			if s.batchMode() {
+				batch.add(item.Data, drip, dripAmount)
+				recset[item.Data.To] = true
+				if len(batch.drips) >= s.BatchSize {
+					if err := s.sendBatch(ctx, s.pickWallet(wallets), batch); err != nil {
+						return err
+					}
+					batch = new(dripBatch)
+				}
+				continue
+			}
+
+			w = s.pickWallet(wallets)
+			tx, err = s.makeDripTx(ctx, w, w.nonce.next, common.HexToAddress(item.Data.To), dripAmount, nil)
 			if err != nil {
 				return err
 			}
@@ -121,53 +287,79 @@ func (s *Faucet) tryToSendDrip(ctx context.Context, bridgeTokens map[string]stri
 				return err
 			}
 
-			drip = &repository.Drip{
-				Pid:    item.Data.Id,
-				Txid:   tx.Hash().String(),
-				From:   s.Account.Hex(),
-				To:     item.Data.To,
-				Amount: utils.ToEther(dripAmount),
-				Rawtx:  rawtx,
-			}
+			drip.Txid = tx.Hash().String()
+			drip.From = w.Account.Hex()
+			drip.Nonce = tx.Nonce()
+			drip.Rawtx = rawtx
 			recset[item.Data.To] = true
 		}
 		if err := s.Repositroy.NewDrip(ctx, item.Data, drip); err != nil {
-			return err
+			var limited repository.ErrDripLimited
+			if !errors.As(err, &limited) {
+				return err
+			}
+			// the tx is never sent, its nonce is used by the next drip
+			logrus.Infof("Don't need to give a drip: %s", limited)
+			if err := s.saveDecision(ctx, nil, policy.Name, item.Data, repository.DecisionIgnore, ReasonRateLimited, limited.Error(), 0, 0); err != nil {
+				return err
+			}
+			tx, drip = nil, nil
+			if err := s.Repositroy.NewDrip(ctx, item.Data, nil); err != nil {
+				return err
+			}
 		}
 		if tx != nil && drip != nil {
-			s.nonce += 1
-			logrus.Infof("Drip: send %f Metis to %s [ Tx %s ]", drip.Amount, drip.To, drip.Txid)
-			if err := s.MetisClient.SendTransaction(ctx, tx); err != nil {
-				return err
+			w.nonce.next++
+			logrus.Infof("Drip: send %f Metis to %s [ From %s Nonce %d Tx %s ]", drip.Amount, drip.To, drip.From, drip.Nonce, drip.Txid)
+			// the drip is persisted already, it will be broadcasted again by the drip checking
+			if err := s.sendTx(ctx, tx); err != nil {
+				return fmt.Errorf("send drip %s: %w", drip.Txid, err)
 			}
 		}
 	}
-	return nil
+	if len(batch.drips) == 0 {
+		return nil
+	}
+	return s.sendBatch(ctx, s.pickWallet(wallets), batch)
 }
 
-func (s *Faucet) shouldTransfer(basectx context.Context, pc *policy.Drip, item *repository.Deposit, recset map[string]bool, bridgeTokens map[string]string) (err error) {
+// shouldTransfer checks the deposit against the policy, the outcomes are collected by the recorder if it's not nil
+func (s *Faucet) shouldTransfer(basectx context.Context, pc *policy.Drip, item *repository.Deposit, recset map[string]bool, bridgeTokens map[string]string, rec *decisionRecorder) (err error) {
+	defer func() {
+		if v, ok := err.(ErrorNoNeedToTransfer); ok {
+			rec.fail(v.code, v.msg)
+		}
+	}()
+
 	if pc == nil {
-		return ErrorNoNeedToTransfer{msg: "No policy found"}
+		return ErrorNoNeedToTransfer{code: ReasonNoPolicy, msg: "No policy found"}
 	}
+	rec.pass(checkPolicy, pc.Name)
 
 	if _, support := bridgeTokens[item.L2Token]; !support {
-		return ErrorNoNeedToTransfer{msg: fmt.Sprintf("%s token is not supported", item.L2Token)}
+		return ErrorNoNeedToTransfer{code: ReasonTokenUnsupported, msg: fmt.Sprintf("%s token is not supported", item.L2Token)}
 	}
+	rec.pass(checkToken, item.L2Token)
 
 	if recset[item.To] {
-		return ErrorNoNeedToTransfer{msg: "has transfered in current loop"}
+		return ErrorNoNeedToTransfer{code: ReasonDuplicateInLoop, msg: "has transfered in current loop"}
 	}
 
 	newctx, cancel := context.WithTimeout(basectx, time.Second*10)
 	defer cancel()
 
+	// the deposit passing the loop checks is scored before the amount and account checks
+	if err := s.checkSybil(newctx, pc, item, rec); err != nil {
+		return err
+	}
+
 	if pc.MinUSDEqual > 0 {
 		var rate float64 = 1
 		if !utils.IsStableL1Token(item.L1Token) {
 			tokenInfo, err := s.Uniswap.GetToken(newctx, item.L1Token)
 			if err != nil {
 				if err == utils.ErrNoTokenInfo {
-					return ErrorNoNeedToTransfer{msg: err.Error()}
+					return ErrorNoNeedToTransfer{code: ReasonNoTokenInfo, msg: err.Error()}
 				}
 				return err
 			}
@@ -186,19 +378,23 @@ func (s *Faucet) shouldTransfer(basectx context.Context, pc *policy.Drip, item *
 			}
 		}
 
-		if amount := item.Amount.Readable(int64(decimal)); rate*amount < pc.MinUSDEqual {
-			return ErrorNoNeedToTransfer{msg: fmt.Sprintf("Amount %f < Min %f USD", rate*amount, pc.MinUSDEqual)}
+		amount := item.Amount.Readable(int64(decimal))
+		rec.setPrice(rate, rate*amount)
+		if rate*amount < pc.MinUSDEqual {
+			return ErrorNoNeedToTransfer{code: ReasonAmountTooLow, msg: fmt.Sprintf("Amount %f < Min %f USD", rate*amount, pc.MinUSDEqual)}
 		}
+		rec.pass(checkMinUSD, fmt.Sprintf("Amount %f >= Min %f USD", rate*amount, pc.MinUSDEqual))
 	}
 
 	if pc.CheckIfFirst {
-		first, err := s.Repositroy.HasGotDrip(newctx, item.To)
+		first, err := s.Repositroy.HasGotDrip(newctx, s.L2ChainId, item.To, item.Id)
 		if err != nil {
 			return err
 		}
 		if !first {
-			return ErrorNoNeedToTransfer{msg: "transfered before"}
+			return ErrorNoNeedToTransfer{code: ReasonNotFirstDrip, msg: "transfered before"}
 		}
+		rec.pass(checkFirstDrip, "")
 
 		// should be a fresh address
 		nonce, err := s.MetisClient.NonceAt(newctx, common.HexToAddress(item.To), nil)
@@ -206,8 +402,21 @@ func (s *Faucet) shouldTransfer(basectx context.Context, pc *policy.Drip, item *
 			return err
 		}
 		if nonce > 0 {
-			return ErrorNoNeedToTransfer{msg: "nonce > 0"}
+			return ErrorNoNeedToTransfer{code: ReasonNonceNotZero, msg: "nonce > 0"}
 		}
+		rec.pass(checkFresh, "")
+	}
+
+	for _, limit := range s.dripLimits(pc, item) {
+		count, err := s.Repositroy.CountDrips(newctx, limit)
+		if err != nil {
+			return err
+		}
+		// it's checked again atomically when the drip is saved
+		if count >= limit.MaxDrips {
+			return ErrorNoNeedToTransfer{code: ReasonRateLimited, msg: fmt.Sprintf("%s has got %d drips in %s", limit.Kind, count, limit.Window)}
+		}
+		rec.pass(checkRateLimit, fmt.Sprintf("%s has got %d drips in %s", limit.Kind, count, limit.Window))
 	}
 
 	if pc.CheckIfNoGas {
@@ -217,8 +426,9 @@ func (s *Faucet) shouldTransfer(basectx context.Context, pc *policy.Drip, item *
 			return err
 		}
 		if balance.Sign() > 0 {
-			return ErrorNoNeedToTransfer{msg: "metis balance > 0"}
+			return ErrorNoNeedToTransfer{code: ReasonHasGas, msg: "metis balance > 0"}
 		}
+		rec.pass(checkNoGas, "")
 	}
 
 	// should be an EOA
@@ -227,39 +437,52 @@ func (s *Faucet) shouldTransfer(basectx context.Context, pc *policy.Drip, item *
 		return err
 	}
 	if len(code) > 0 {
-		return ErrorNoNeedToTransfer{msg: "not EOA"}
+		return ErrorNoNeedToTransfer{code: ReasonNotEOA, msg: "not EOA"}
 	}
+	rec.pass(checkEOA, "")
 
 	return nil
 }
 
-func (s *Faucet) makeDripTx(basectx context.Context, toAddr string, amount *big.Int) (*types.Transaction, error) {
+// dripLimits returns the rate limits of the policy for the deposit
+func (s *Faucet) dripLimits(pc *policy.Drip, item *repository.Deposit) []repository.DripLimit {
+	var limits []repository.DripLimit
+	if pc.RecipientLimit.Enabled() {
+		limits = append(limits, repository.DripLimit{
+			ChainId: s.L2ChainId, Kind: repository.DripLimitRecipient, Address: item.To,
+			MaxDrips: pc.RecipientLimit.MaxDrips, Window: pc.RecipientLimit.Window,
+		})
+	}
+	if pc.SenderLimit.Enabled() {
+		limits = append(limits, repository.DripLimit{
+			ChainId: s.L2ChainId, Kind: repository.DripLimitSender, Address: item.From,
+			MaxDrips: pc.SenderLimit.MaxDrips, Window: pc.SenderLimit.Window,
+		})
+	}
+	return limits
+}
+
+func (s *Faucet) makeDripTx(basectx context.Context, w *Wallet, nonce uint64, receiver common.Address, amount *big.Int, data []byte) (*types.Transaction, error) {
 	newctx, cancel := context.WithTimeout(basectx, time.Second*5)
 	defer cancel()
 
-	gasPrice, err := s.MetisClient.SuggestGasPrice(newctx)
+	gas, err := s.MetisClient.EstimateGas(newctx,
+		ethereum.CallMsg{From: w.Account, To: &receiver, Value: amount, Data: data})
 	if err != nil {
 		return nil, err
 	}
 
-	receiver := common.HexToAddress(toAddr)
-	gas, err := s.MetisClient.EstimateGas(newctx,
-		ethereum.CallMsg{From: s.Account, To: &receiver, Value: amount})
+	rawtx, err := s.makeTxData(newctx, nonce, gas, receiver, amount, data)
 	if err != nil {
 		return nil, err
 	}
-
-	rawtx := &types.LegacyTx{
-		Nonce:    s.nonce,
-		GasPrice: gasPrice,
-		Gas:      gas,
-		To:       &receiver,
-		Value:    amount,
-	}
-	return types.SignNewTx(s.Prvkey, s.Eip155Signer, rawtx)
+	return w.sign(newctx, s.Signer, rawtx)
 }
 
 func (s *Faucet) CheckDrips(basectx context.Context) {
+	if s.DryRun != "" {
+		return
+	}
 	newctx, cancel := context.WithTimeout(basectx, time.Minute*5)
 	defer cancel()
 	if err := s.tryToCheckDrip(newctx); err != nil {
@@ -268,50 +491,166 @@ func (s *Faucet) CheckDrips(basectx context.Context) {
 }
 
 func (s *Faucet) tryToCheckDrip(ctx context.Context) error {
+	// a failed wallet doesn't block checking the others
+	for _, w := range s.Wallets {
+		if err := s.checkWalletDrips(ctx, w); err != nil {
+			logrus.Errorf("failed to check drips of wallet %s: %s", w.Account, err)
+		}
+	}
+
+	failed, err := s.Repositroy.GetFailedDepositCount(ctx, s.L2ChainId)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		logrus.Warnf("%d deposits have reverted drips, retry them with the retry subcommand", failed)
+	}
+	return nil
+}
+
+// checkWalletDrips checks the pending drips and batches sent from the wallet, and recovers its nonce gaps
+func (s *Faucet) checkWalletDrips(ctx context.Context, w *Wallet) error {
 	// check if current balance is less than reserved balance
-	balance, err := s.MetisClient.BalanceAt(ctx, s.Account, nil)
+	balance, err := s.MetisClient.BalanceAt(ctx, w.Account, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get balance: %w", err)
 	}
+	if m, threshold := utils.ToEther(balance), s.walletThreshold(w); m < threshold {
+		logrus.Warnf("Wallet %s balance %f is less than min reserved %f", w.Account, m, threshold)
+	}
 
-	if m := utils.ToEther(balance); m < s.ReservedBalance {
-		return fmt.Errorf("current balance %f is less than min reserved %f", m, s.ReservedBalance)
+	if err := s.reconcileNonce(ctx, w); err != nil {
+		return err
 	}
 
-	for item := range s.Repositroy.GetPendingDripsStream(ctx) {
+	for item := range s.Repositroy.GetPendingDripsStream(ctx, s.L2ChainId, w.Account.Hex()) {
 		if item.Error != nil {
 			return item.Error
 		}
-		done, err := s.getTxStatus(ctx, item.Data)
-		if err != nil {
+		if err := s.checkPendingDrip(ctx, w, item.Data); err != nil {
 			return err
 		}
-		if !done {
-			var tx = new(types.Transaction)
-			_ = tx.UnmarshalBinary(item.Data.Rawtx)
-			_ = s.MetisClient.SendTransaction(ctx, tx)
-			continue
+	}
+
+	batches, err := s.Repositroy.GetPendingBatches(ctx, s.L2ChainId, w.Account.Hex())
+	if err != nil {
+		return err
+	}
+	for _, item := range batches {
+		if err := s.checkPendingDrip(ctx, w, item); err != nil {
+			return err
 		}
-		logrus.Infof("Updating deposit %d status [ Tx %s ]", item.Data.Id, item.Data.Txid)
-		if err := s.Repositroy.UpdateDripStatus(ctx, item.Data.Id, repository.DepositStatusDone); err != nil {
+	}
+	return s.fillNonceGaps(ctx, w)
+}
+
+// checkPendingDrip records the receipt of the drip or the batch if it's mined,
+// otherwise it's re-signed if its nonce is taken, replaced if it's stuck or broadcasted again.
+func (s *Faucet) checkPendingDrip(ctx context.Context, w *Wallet, drip *repository.PendingDrip) error {
+	receipt, err := s.getTxStatus(ctx, drip)
+	if err != nil {
+		return err
+	}
+	if receipt != nil {
+		return s.saveReceipt(ctx, drip, receipt)
+	}
+
+	var tx = new(types.Transaction)
+	if err := tx.UnmarshalBinary(drip.Rawtx); err != nil {
+		return fmt.Errorf("decode drip %s: %w", drip.Txid, err)
+	}
+	if drip.Nonce < w.nonce.confirmed {
+		return s.resignDrip(ctx, w, drip, tx)
+	}
+	if s.StuckAge > 0 && time.Since(drip.SentAt) > s.StuckAge {
+		replaced, err := s.replaceDrip(ctx, w, drip, tx)
+		if err != nil || replaced {
 			return err
 		}
 	}
+	if err := s.sendTx(ctx, tx); err != nil {
+		if !isNonceTooLowError(err) {
+			return fmt.Errorf("broadcast drip %s: %w", drip.Txid, err)
+		}
+		return s.resignDrip(ctx, w, drip, tx)
+	}
 	return nil
 }
 
-func (s *Faucet) getTxStatus(ctx context.Context, tx *repository.PendingDrip) (bool, error) {
-	newctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
+func (s *Faucet) saveReceipt(ctx context.Context, drip *repository.PendingDrip, receipt *types.Receipt) error {
+	gasPrice, err := s.getEffectiveGasPrice(ctx, receipt)
+	if err != nil {
+		return err
+	}
+	res := &repository.DripReceipt{
+		Txid:              receipt.TxHash.Hex(),
+		Status:            receipt.Status,
+		BlockNumber:       receipt.BlockNumber.Uint64(),
+		Blockhash:         receipt.BlockHash.Hex(),
+		GasUsed:           receipt.GasUsed,
+		EffectiveGasPrice: bigint.FromBigInt(gasPrice),
+	}
+
+	if drip.BatchId > 0 {
+		drips, err := s.Repositroy.GetBatchDrips(ctx, drip.BatchId)
+		if err != nil {
+			return err
+		}
+		received := s.getBatchReceived(receipt, drips)
+		logrus.Infof("Updating drip batch %d status: Received %d [ Tx %s ]", drip.BatchId, len(received), receipt.TxHash)
+		return s.Repositroy.SaveBatchReceipt(ctx, drip.BatchId, res, received)
+	}
 
-	_, err := s.MetisClient.TransactionReceipt(newctx, common.HexToHash(tx.Txid))
+	if receipt.Status == types.ReceiptStatusFailed {
+		logrus.Errorf("Drip of deposit %d is reverted, it's waiting for a retry [ Tx %s ]", drip.Id, receipt.TxHash)
+	} else {
+		logrus.Infof("Updating deposit %d status [ Tx %s ]", drip.Id, receipt.TxHash)
+	}
+	return s.Repositroy.SaveDripReceipt(ctx, drip.Id, res)
+}
+
+// getTxStatus returns the receipt of the mined one of the drip and its replacements, it's nil if none is mined yet
+func (s *Faucet) getTxStatus(ctx context.Context, tx *repository.PendingDrip) (*types.Receipt, error) {
+	txids, err := s.getDripTxids(ctx, tx)
 	if err != nil {
-		if err == ethereum.NotFound {
-			return false, nil
+		return nil, err
+	}
+
+	for _, txid := range txids {
+		newctx, cancel := context.WithTimeout(ctx, time.Second)
+		receipt, err := s.MetisClient.TransactionReceipt(newctx, common.HexToHash(txid))
+		cancel()
+		if err != nil {
+			if err == ethereum.NotFound {
+				continue
+			}
+			return nil, err
 		}
-		return false, err
+		return receipt, nil
 	}
-	return true, nil
+	return nil, nil
+}
+
+// getDripTxids returns the transaction hashes signed for the drip or the batch, the latest one is the first
+func (s *Faucet) getDripTxids(ctx context.Context, drip *repository.PendingDrip) ([]string, error) {
+	if drip.BatchId > 0 {
+		return s.Repositroy.GetBatchTxids(ctx, drip.BatchId)
+	}
+	return s.Repositroy.GetDripTxids(ctx, drip.Id)
+}
+
+// getEffectiveGasPrice returns the gas price paid by the drip, the legacy nodes don't return it with the receipt
+func (s *Faucet) getEffectiveGasPrice(ctx context.Context, receipt *types.Receipt) (*big.Int, error) {
+	if receipt.EffectiveGasPrice != nil {
+		return receipt.EffectiveGasPrice, nil
+	}
+	newctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	tx, _, err := s.MetisClient.TransactionByHash(newctx, receipt.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("get drip %s: %w", receipt.TxHash, err)
+	}
+	return tx.GasPrice(), nil
 }
 
 func (s *Faucet) calMetisDrip(ctx context.Context, pc *policy.Drip, txHash string) (*big.Int, error) {
@@ -347,18 +686,3 @@ func (s *Faucet) calMetisDrip(ctx context.Context, pc *policy.Drip, txHash strin
 
 	return nil, fmt.Errorf("not supported rebate type: %v", pc.RebateType)
 }
-
-func (s *Faucet) checkBalance(ctx context.Context) error {
-	newctx, cancel := context.WithTimeout(ctx, time.Second*3)
-	defer cancel()
-
-	balance, err := s.MetisClient.BalanceAt(newctx, s.Account, nil)
-	if err != nil {
-		return err
-	}
-
-	if balance.Cmp(big.NewInt(5e17)) < 0 {
-		return fmt.Errorf("insufficient Balance: %f < 0.5 Metis", utils.ToEther(balance))
-	}
-	return nil
-}
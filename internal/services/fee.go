@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// FeeMode decides the transaction type of the drips
+type FeeMode string
+
+const (
+	// dynamic fee transactions if the layer2 supports London, legacy ones otherwise
+	FeeModeAuto FeeMode = "auto"
+	// always EIP-1559 dynamic fee transactions
+	FeeModeDynamic FeeMode = "dynamic"
+	// always legacy transactions with the suggested gas price
+	FeeModeLegacy FeeMode = "legacy"
+)
+
+func (m FeeMode) Valid() bool {
+	switch m {
+	case FeeModeAuto, FeeModeDynamic, FeeModeLegacy:
+		return true
+	default:
+		return false
+	}
+}
+
+// detectFeeMode decides whether the drips are dynamic fee transactions by the base fee of the latest header
+func (s *Faucet) detectFeeMode(ctx context.Context) error {
+	switch s.FeeMode {
+	case FeeModeLegacy:
+		s.dynamicFee = false
+	case FeeModeDynamic:
+		s.dynamicFee = true
+	default:
+		header, err := s.MetisClient.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("detectFeeMode: get latest header: %w", err)
+		}
+		s.dynamicFee = header.BaseFee != nil
+	}
+	logrus.Infof("Drip fee mode: %s DynamicFee %v", s.FeeMode, s.dynamicFee)
+	return nil
+}
+
+// makeTxData builds the unsigned drip transaction with the fees of the current fee mode
+func (s *Faucet) makeTxData(ctx context.Context, nonce uint64, gas uint64, receiver common.Address, amount *big.Int, data []byte) (types.TxData, error) {
+	if !s.dynamicFee {
+		gasPrice, err := s.MetisClient.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: gasPrice,
+			Gas:      gas,
+			To:       &receiver,
+			Value:    amount,
+			Data:     data,
+		}, nil
+	}
+
+	header, err := s.MetisClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if header.BaseFee == nil {
+		return nil, fmt.Errorf("layer2 doesn't support dynamic fee transactions")
+	}
+	tip, err := s.MetisClient.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tip = mulFee(tip, s.TipMultiplier)
+	// the fee cap keeps the drip valid in the next blocks even the base fee keeps growing
+	feeCap := new(big.Int).Add(mulFee(header.BaseFee, s.FeeCapMultiplier), tip)
+	return &types.DynamicFeeTx{
+		ChainID:   new(big.Int).SetUint64(s.L2ChainId),
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        &receiver,
+		Value:     amount,
+		Data:      data,
+	}, nil
+}
+
+// the percentage a replacement bumps the fees by, it's above the 10% required by geth's transaction pool
+const replacementBump = 15
+
+// makeReplacementTxData builds the transaction replacing the stuck one at the same nonce,
+// the fees are the current suggested ones or the bumped old ones, whichever is higher, and capped at the max fee.
+// It returns false if the capped fees can't bump the old ones enough to replace it.
+func (s *Faucet) makeReplacementTxData(ctx context.Context, old *types.Transaction) (types.TxData, bool, error) {
+	data, err := s.makeTxData(ctx, old.Nonce(), old.Gas(), *old.To(), old.Value(), old.Data())
+	if err != nil {
+		return nil, false, err
+	}
+	switch tx := data.(type) {
+	case *types.LegacyTx:
+		tx.GasPrice = bigMax(tx.GasPrice, bumpFee(old.GasPrice()))
+	case *types.DynamicFeeTx:
+		// a legacy transaction's tip and fee cap are both its gas price
+		tx.GasTipCap = bigMax(tx.GasTipCap, bumpFee(old.GasTipCap()))
+		tx.GasFeeCap = bigMax(tx.GasFeeCap, bumpFee(old.GasFeeCap()), tx.GasTipCap)
+	}
+	return data, capFees(data, old, s.MaxFee), nil
+}
+
+// capFees caps the fees of the replacement at the max fee, nil or 0 disables the cap,
+// it returns false if the capped fees are not bumped enough from the old ones.
+func capFees(data types.TxData, old *types.Transaction, maxFee *big.Int) bool {
+	if maxFee == nil || maxFee.Sign() <= 0 {
+		return true
+	}
+	switch tx := data.(type) {
+	case *types.LegacyTx:
+		tx.GasPrice = bigMin(tx.GasPrice, maxFee)
+		return tx.GasPrice.Cmp(bumpFee(old.GasPrice())) >= 0
+	case *types.DynamicFeeTx:
+		tx.GasFeeCap = bigMin(tx.GasFeeCap, maxFee)
+		tx.GasTipCap = bigMin(tx.GasTipCap, tx.GasFeeCap)
+		return tx.GasFeeCap.Cmp(bumpFee(old.GasFeeCap())) >= 0 && tx.GasTipCap.Cmp(bumpFee(old.GasTipCap())) >= 0
+	}
+	return true
+}
+
+// replaceDrip re-signs the stuck drip at the same nonce with bumped fees,
+// it returns false if the fees of the drip have reached the max fee, the drip is broadcasted again as it is then.
+func (s *Faucet) replaceDrip(ctx context.Context, w *Wallet, drip *repository.PendingDrip, oldtx *types.Transaction) (bool, error) {
+	newctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	data, ok, err := s.makeReplacementTxData(newctx, oldtx)
+	if err != nil {
+		return false, fmt.Errorf("replaceDrip: %w", err)
+	}
+	if !ok {
+		logrus.Warnf("Drip is stuck since %s, but its fee cap %s has reached the max fee %s [ Nonce %d Tx %s ]",
+			drip.SentAt.Format(time.RFC3339), oldtx.GasFeeCap(), s.MaxFee, oldtx.Nonce(), drip.Txid)
+		return false, nil
+	}
+	tx, err := w.sign(newctx, s.Signer, data)
+	if err != nil {
+		return false, fmt.Errorf("replaceDrip: %w", err)
+	}
+	rawtx, err := tx.MarshalBinary()
+	if err != nil {
+		return false, fmt.Errorf("replaceDrip: %w", err)
+	}
+	if err := s.updateDripTx(ctx, drip, tx.Hash().Hex(), tx.Nonce(), rawtx); err != nil {
+		return false, fmt.Errorf("replaceDrip: %w", err)
+	}
+
+	logrus.Warnf("Drip is stuck since %s, replaced with the fee cap %s [ Nonce %d Old %s New %s ]",
+		drip.SentAt.Format(time.RFC3339), tx.GasFeeCap(), tx.Nonce(), drip.Txid, tx.Hash())
+	if err := s.sendTx(ctx, tx); err != nil {
+		// it will be bumped again after the stuck age
+		logrus.Warnf("Broadcast the replacement %s: %s", tx.Hash(), err)
+	}
+	return true, nil
+}
+
+func bumpFee(fee *big.Int) *big.Int {
+	res := new(big.Int).Mul(fee, big.NewInt(100+replacementBump))
+	res.Add(res, big.NewInt(99))
+	return res.Div(res, big.NewInt(100))
+}
+
+func bigMax(x *big.Int, others ...*big.Int) *big.Int {
+	res := x
+	for _, item := range others {
+		if item.Cmp(res) > 0 {
+			res = item
+		}
+	}
+	return new(big.Int).Set(res)
+}
+
+func bigMin(x, y *big.Int) *big.Int {
+	if y.Cmp(x) < 0 {
+		return new(big.Int).Set(y)
+	}
+	return new(big.Int).Set(x)
+}
+
+// mulFee multiplies the fee by the multiplier, the multiplier less than or equal to 0 is treated as 1
+func mulFee(fee *big.Int, multiplier float64) *big.Int {
+	if multiplier <= 0 {
+		return new(big.Int).Set(fee)
+	}
+	res, _ := new(big.Float).Mul(new(big.Float).SetInt(fee), big.NewFloat(multiplier)).Int(nil)
+	return res
+}
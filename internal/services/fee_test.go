@@ -0,0 +1,81 @@
+package services
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestMulFee(t *testing.T) {
+	tests := []struct {
+		name       string
+		fee        int64
+		multiplier float64
+		want       int64
+	}{
+		{"double", 1e9, 2, 2e9},
+		{"fraction", 1e9, 1.25, 1.25e9},
+		{"floor", 3, 0.5, 1},
+		{"invalid", 1e9, 0, 1e9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mulFee(big.NewInt(tt.fee), tt.multiplier); got.Int64() != tt.want {
+				t.Errorf("mulFee() = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBumpFee(t *testing.T) {
+	tests := []struct {
+		fee  int64
+		want int64
+	}{
+		{1e9, 1.15e9},
+		// rounded up to always meet the replacement rule
+		{1, 2},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := bumpFee(big.NewInt(tt.fee)); got.Int64() != tt.want {
+			t.Errorf("bumpFee(%d) = %s, want %d", tt.fee, got, tt.want)
+		}
+	}
+}
+
+func TestCapFees(t *testing.T) {
+	const gwei = 1e9
+	dynamic := func(tip, feeCap int64) *types.DynamicFeeTx {
+		return &types.DynamicFeeTx{GasTipCap: big.NewInt(tip), GasFeeCap: big.NewInt(feeCap)}
+	}
+	tests := []struct {
+		name    string
+		old     types.TxData
+		data    types.TxData
+		maxFee  int64
+		want    bool
+		wantTip int64
+		wantCap int64
+	}{
+		{"no cap", dynamic(1*gwei, 80*gwei), dynamic(1.15*gwei, 92*gwei), 0, true, 1.15 * gwei, 92 * gwei},
+		{"below the cap", dynamic(1*gwei, 80*gwei), dynamic(1.15*gwei, 92*gwei), 100 * gwei, true, 1.15 * gwei, 92 * gwei},
+		{"capped but bumped enough", dynamic(1*gwei, 80*gwei), dynamic(1.15*gwei, 120*gwei), 95 * gwei, true, 1.15 * gwei, 95 * gwei},
+		{"capped tip", dynamic(80*gwei, 80*gwei), dynamic(92*gwei, 92*gwei), 85 * gwei, false, 85 * gwei, 85 * gwei},
+		{"cap reached", dynamic(1*gwei, 100*gwei), dynamic(1.15*gwei, 115*gwei), 100 * gwei, false, 1.15 * gwei, 100 * gwei},
+		{"legacy cap reached", &types.LegacyTx{GasPrice: big.NewInt(100 * gwei)}, &types.LegacyTx{GasPrice: big.NewInt(115 * gwei)}, 100 * gwei, false, 100 * gwei, 100 * gwei},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := capFees(tt.data, types.NewTx(tt.old), big.NewInt(tt.maxFee))
+			if got != tt.want {
+				t.Errorf("capFees() = %v, want %v", got, tt.want)
+			}
+			tx := types.NewTx(tt.data)
+			if tx.GasTipCap().Int64() != tt.wantTip || tx.GasFeeCap().Int64() != tt.wantCap {
+				t.Errorf("capFees() fees = %s/%s, want %d/%d", tx.GasTipCap(), tx.GasFeeCap(), tt.wantTip, tt.wantCap)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+// getFunder returns the layer1 funder of the deposit sender, it's traced once for a sender and saved with the deposit
+func (s *Faucet) getFunder(ctx context.Context, item *repository.Deposit) (string, error) {
+	if item.Funder != nil {
+		return *item.Funder, nil
+	}
+
+	funder, err := s.Repositroy.GetFunder(ctx, item.From)
+	if err != nil {
+		return "", err
+	}
+	if funder == nil {
+		traced, err := s.traceFunder(ctx, common.HexToAddress(item.From), item.Height)
+		if err != nil {
+			return "", err
+		}
+		funder = &traced
+	}
+
+	// the funder is a fact of the layer1 chain, the dry-run faucet saves it as well
+	if err := s.Repositroy.SetFunder(ctx, item.Id, *funder); err != nil {
+		return "", err
+	}
+	item.Funder = funder
+	return *funder, nil
+}
+
+// traceFunder finds the block the sender got its first ether and returns the sender of the transfer in it,
+// it's empty if the ether comes from an internal transfer of a contract, it needs an archive node for the historical balances.
+func (s *Faucet) traceFunder(ctx context.Context, sender common.Address, height uint64) (string, error) {
+	if height == 0 {
+		return "", nil
+	}
+	// the sender pays the gas of the deposit, so it has ether before the deposit block
+	funded, err := searchFundedHeight(ctx, height-1, func(ctx context.Context, number uint64) (bool, error) {
+		balance, err := s.EthClient.BalanceAt(ctx, sender, new(big.Int).SetUint64(number))
+		if err != nil {
+			return false, fmt.Errorf("get balance of %s at %d: %w", sender, number, err)
+		}
+		return balance.Sign() > 0, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("traceFunder: %w", err)
+	}
+
+	block, err := s.EthClient.BlockByNumber(ctx, new(big.Int).SetUint64(funded))
+	if err != nil {
+		return "", fmt.Errorf("traceFunder: get block %d: %w", funded, err)
+	}
+	for _, tx := range block.Transactions() {
+		if tx.To() == nil || *tx.To() != sender || tx.Value().Sign() <= 0 {
+			continue
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return "", fmt.Errorf("traceFunder: get sender of %s: %w", tx.Hash(), err)
+		}
+		return from.Hex(), nil
+	}
+	return "", nil
+}
+
+// searchFundedHeight returns the lowest height not higher than the latest one from which the account has ether
+func searchFundedHeight(ctx context.Context, latest uint64, hasBalance func(context.Context, uint64) (bool, error)) (uint64, error) {
+	low, high := uint64(0), latest
+	for low < high {
+		mid := low + (high-low)/2
+		ok, err := hasBalance(ctx, mid)
+		if err != nil {
+			return 0, fmt.Errorf("searchFundedHeight: %w", err)
+		}
+		if ok {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return low, nil
+}
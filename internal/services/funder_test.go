@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchFundedHeight(t *testing.T) {
+	tests := []struct {
+		name   string
+		funded uint64
+		latest uint64
+		want   uint64
+	}{
+		{"funded at genesis", 0, 1000, 0},
+		{"funded in the middle", 421, 1000, 421},
+		{"funded at the latest", 1000, 1000, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := searchFundedHeight(context.Background(), tt.latest, func(_ context.Context, number uint64) (bool, error) {
+				return number >= tt.funded, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("searchFundedHeight() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// nonceTracker tracks the nonces of the faucet account,
+// the assigned nonces are persisted with the drips and reconciled with the layer2 node on every loop.
+type nonceTracker struct {
+	// the next nonce to assign to a new drip
+	next uint64
+	// the nonce of the next transaction to be mined
+	confirmed uint64
+	// the nonce after the transactions in the node's pool
+	pending uint64
+}
+
+// gaps returns the assigned nonces which are neither held by the given pending drips nor the node's pool
+func (n nonceTracker) gaps(held []uint64) []uint64 {
+	set := make(map[uint64]bool, len(held))
+	for _, nonce := range held {
+		set[nonce] = true
+	}
+	var res []uint64
+	// the nonces lower than the pending one are held by the transactions in the node's pool
+	for nonce := max(n.confirmed, n.pending); nonce < n.next; nonce++ {
+		if !set[nonce] {
+			res = append(res, nonce)
+		}
+	}
+	return res
+}
+
+// reconcileNonce syncs the nonce tracker with the persisted drips and the layer2 node
+func (s *Faucet) reconcileNonce(basectx context.Context, w *Wallet) error {
+	newctx, cancel := context.WithTimeout(basectx, time.Second*10)
+	defer cancel()
+
+	confirmed, err := s.MetisClient.NonceAt(newctx, w.Account, nil)
+	if err != nil {
+		return fmt.Errorf("reconcileNonce: get nonce: %w", err)
+	}
+	pending, err := s.MetisClient.PendingNonceAt(newctx, w.Account)
+	if err != nil {
+		return fmt.Errorf("reconcileNonce: get pending nonce: %w", err)
+	}
+	stored, ok, err := s.Repositroy.GetMaxDripNonce(newctx, s.L2ChainId, w.Account.Hex())
+	if err != nil {
+		return fmt.Errorf("reconcileNonce: %w", err)
+	}
+
+	next := max(confirmed, pending)
+	if ok {
+		next = max(next, stored+1)
+	}
+	if next != w.nonce.next {
+		logrus.Infof("Nonce reconciled: Wallet %s Next %d Confirmed %d Pending %d", w.Account, next, confirmed, pending)
+	}
+	w.nonce = nonceTracker{next: next, confirmed: confirmed, pending: pending}
+	return nil
+}
+
+// fillNonceLegacy decodes the nonces of the legacy drips saved before the nonce is recorded
+func (s *Faucet) fillNonceLegacy(ctx context.Context) error {
+	drips, err := s.Repositroy.GetDripsWithoutNonce(ctx)
+	if err != nil {
+		return fmt.Errorf("fillNonceLegacy: %w", err)
+	}
+	for _, item := range drips {
+		var tx = new(types.Transaction)
+		if err := tx.UnmarshalBinary(item.Rawtx); err != nil {
+			return fmt.Errorf("fillNonceLegacy: decode drip %s: %w", item.Txid, err)
+		}
+		if err := s.Repositroy.SetDripNonce(ctx, item.Id, tx.Nonce()); err != nil {
+			return fmt.Errorf("fillNonceLegacy: %w", err)
+		}
+	}
+	if len(drips) > 0 {
+		logrus.Infof("Filled the nonce of %d legacy drips", len(drips))
+	}
+	return nil
+}
+
+// fillNonceGaps sends no-op self transfers for the nonces which are assigned but not held by any pending drip,
+// otherwise the later drips are stuck behind the gaps forever.
+func (s *Faucet) fillNonceGaps(ctx context.Context, w *Wallet) error {
+	nonces, err := s.Repositroy.GetPendingDripNonces(ctx, s.L2ChainId, w.Account.Hex())
+	if err != nil {
+		return fmt.Errorf("fillNonceGaps: %w", err)
+	}
+	for _, nonce := range w.nonce.gaps(nonces) {
+		tx, err := s.makeDripTx(ctx, w, nonce, w.Account, common.Big0, nil)
+		if err != nil {
+			return fmt.Errorf("fillNonceGaps: %w", err)
+		}
+		logrus.Warnf("Filling nonce gap %d of %s with a self transfer [ Tx %s ]", nonce, w.Account, tx.Hash())
+		if err := s.sendTx(ctx, tx); err != nil && !isNonceTooLowError(err) {
+			return fmt.Errorf("fillNonceGaps: %w", err)
+		}
+	}
+	return nil
+}
+
+// resignDrip signs the pending drip again with a new nonce after its nonce is taken by another transaction
+func (s *Faucet) resignDrip(ctx context.Context, w *Wallet, drip *repository.PendingDrip, oldtx *types.Transaction) error {
+	// the drip or one of its replacements is mined but the receipt is not indexed yet
+	mined, err := s.isDripMined(ctx, drip, oldtx)
+	if err != nil {
+		return fmt.Errorf("resignDrip: %w", err)
+	}
+	if mined {
+		return nil
+	}
+
+	nonce := w.nonce.next
+	tx, err := s.makeDripTx(ctx, w, nonce, *oldtx.To(), oldtx.Value(), oldtx.Data())
+	if err != nil {
+		return fmt.Errorf("resignDrip: %w", err)
+	}
+	rawtx, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("resignDrip: %w", err)
+	}
+	if err := s.updateDripTx(ctx, drip, tx.Hash().Hex(), nonce, rawtx); err != nil {
+		return fmt.Errorf("resignDrip: %w", err)
+	}
+	w.nonce.next++
+
+	logrus.Warnf("Drip nonce %d is taken, re-signed with nonce %d [ Old %s New %s ]", drip.Nonce, nonce, drip.Txid, tx.Hash())
+	if err := s.sendTx(ctx, tx); err != nil {
+		return fmt.Errorf("resignDrip: %w", err)
+	}
+	return nil
+}
+
+// isDripMined checks all the transactions signed for the drip, a drip is taken as not mined only if the node doesn't know any of them,
+// the other errors may hide a mined drip and re-signing it pays twice.
+func (s *Faucet) isDripMined(ctx context.Context, drip *repository.PendingDrip, oldtx *types.Transaction) (bool, error) {
+	txids, err := s.getDripTxids(ctx, drip)
+	if err != nil {
+		return false, err
+	}
+	hashes := []common.Hash{oldtx.Hash()}
+	for _, txid := range txids {
+		if hash := common.HexToHash(txid); hash != oldtx.Hash() {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	for _, hash := range hashes {
+		newctx, cancel := context.WithTimeout(ctx, time.Second*5)
+		_, isPending, err := s.MetisClient.TransactionByHash(newctx, hash)
+		cancel()
+		if err != nil {
+			if errors.Is(err, ethereum.NotFound) {
+				continue
+			}
+			return false, fmt.Errorf("isDripMined: get drip %s: %w", hash, err)
+		}
+		if !isPending {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// updateDripTx saves the re-signed transaction of the drip or the batch
+func (s *Faucet) updateDripTx(ctx context.Context, drip *repository.PendingDrip, txid string, nonce uint64, rawtx []byte) error {
+	if drip.BatchId > 0 {
+		return s.Repositroy.UpdateBatchTx(ctx, drip.BatchId, txid, nonce, rawtx)
+	}
+	return s.Repositroy.UpdateDripTx(ctx, drip.Id, txid, nonce, rawtx)
+}
+
+// sendTx broadcasts the transaction, it's fine if the node has known it already
+func (s *Faucet) sendTx(ctx context.Context, tx *types.Transaction) error {
+	newctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	if err := s.MetisClient.SendTransaction(newctx, tx); err != nil && !isKnownTxError(err) {
+		return err
+	}
+	return nil
+}
+
+func isNonceTooLowError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
+
+func isKnownTxError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, keyword := range []string{"already known", "known transaction", "already imported", "already in pool"} {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNonceTracker_Gaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracker nonceTracker
+		held    []uint64
+		want    []uint64
+	}{
+		{"no gap", nonceTracker{next: 8, confirmed: 5, pending: 5}, []uint64{5, 6, 7}, nil},
+		{"send failed", nonceTracker{next: 8, confirmed: 5, pending: 5}, []uint64{5, 7}, []uint64{6}},
+		{"in pool", nonceTracker{next: 8, confirmed: 5, pending: 7}, []uint64{7}, nil},
+		{"external pending", nonceTracker{next: 9, confirmed: 5, pending: 9}, nil, nil},
+		{"not held", nonceTracker{next: 8, confirmed: 6, pending: 6}, []uint64{4}, []uint64{6, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tracker.gaps(tt.held); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gaps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsKnownTxError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"geth", errors.New("already known"), true},
+		{"legacy", errors.New("known transaction: 0x01"), true},
+		{"nonce too low", errors.New("nonce too low: next nonce 6, tx nonce 5"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isKnownTxError(tt.err); got != tt.want {
+				t.Errorf("isKnownTxError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Budget bounds the drips given in a window, the zero limits are unlimited
+type Budget struct {
+	// the windows are aligned to the multiples of it in UTC, 0 is the lifetime budget
+	Window   time.Duration
+	MaxMetis float64
+	MaxUSD   float64
+}
+
+// Since returns the start time of the window containing now
+func (b Budget) Since(now time.Time) time.Time {
+	if b.Window <= 0 {
+		return time.Time{}
+	}
+	return now.Truncate(b.Window)
+}
+
+// Until returns the time the window containing now rolls over, it's zero for the lifetime budget
+func (b Budget) Until(now time.Time) time.Time {
+	if b.Window <= 0 {
+		return time.Time{}
+	}
+	return b.Since(now).Add(b.Window)
+}
+
+func (b Budget) String() string {
+	var window string
+	switch b.Window {
+	case 0:
+		window = "lifetime"
+	case time.Hour:
+		window = "hourly"
+	case time.Hour * 24:
+		window = "daily"
+	default:
+		window = b.Window.String()
+	}
+	var limits []string
+	if b.MaxMetis > 0 {
+		limits = append(limits, fmt.Sprintf("%g Metis", b.MaxMetis))
+	}
+	if b.MaxUSD > 0 {
+		limits = append(limits, fmt.Sprintf("%g USD", b.MaxUSD))
+	}
+	return fmt.Sprintf("%s %s", window, strings.Join(limits, " "))
+}
+
+// ParseBudgets parses the comma separated budgets in the form of <window>=<limit><unit>,
+// the window is hourly, daily, lifetime or a duration, and the unit is metis or usd,
+// e.g. "hourly=100metis,daily=2000usd,lifetime=50000metis".
+// The items of the same window are merged into one budget, e.g. "daily=100metis,daily=2000usd".
+func ParseBudgets(spec string) ([]Budget, error) {
+	var budgets []Budget
+	windows := make(map[time.Duration]int)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		rawWindow, rawLimit, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid budget %q", item)
+		}
+
+		var budget Budget
+		switch rawWindow = strings.ToLower(strings.TrimSpace(rawWindow)); rawWindow {
+		case "hourly":
+			budget.Window = time.Hour
+		case "daily":
+			budget.Window = time.Hour * 24
+		case "lifetime":
+		default:
+			window, err := time.ParseDuration(rawWindow)
+			if err != nil || window <= 0 {
+				return nil, fmt.Errorf("invalid budget window %q", rawWindow)
+			}
+			budget.Window = window
+		}
+
+		rawLimit = strings.ToLower(strings.TrimSpace(rawLimit))
+		var unit string
+		for _, u := range []string{"metis", "usd"} {
+			if strings.HasSuffix(rawLimit, u) {
+				unit, rawLimit = u, strings.TrimSuffix(rawLimit, u)
+			}
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(rawLimit), 64)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid budget limit %q", item)
+		}
+		idx, ok := windows[budget.Window]
+		if !ok {
+			idx = len(budgets)
+			windows[budget.Window] = idx
+			budgets = append(budgets, budget)
+		}
+		switch unit {
+		case "metis":
+			if budgets[idx].MaxMetis > 0 {
+				return nil, fmt.Errorf("duplicate metis budget %q", item)
+			}
+			budgets[idx].MaxMetis = limit
+		case "usd":
+			if budgets[idx].MaxUSD > 0 {
+				return nil, fmt.Errorf("duplicate usd budget %q", item)
+			}
+			budgets[idx].MaxUSD = limit
+		default:
+			return nil, fmt.Errorf("invalid budget unit %q, it should be metis or usd", item)
+		}
+	}
+	return budgets, nil
+}
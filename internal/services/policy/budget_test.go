@@ -0,0 +1,34 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBudgets(t *testing.T) {
+	budgets, err := ParseBudgets("hourly=100metis, daily=2000USD,lifetime=50000metis,30m=5metis,daily=100metis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Budget{
+		{Window: time.Hour, MaxMetis: 100},
+		// the items of the same window are merged
+		{Window: time.Hour * 24, MaxMetis: 100, MaxUSD: 2000},
+		{MaxMetis: 50000},
+		{Window: time.Minute * 30, MaxMetis: 5},
+	}
+	if len(budgets) != len(want) {
+		t.Fatalf("ParseBudgets() = %v, want %v", budgets, want)
+	}
+	for i := range want {
+		if budgets[i] != want[i] {
+			t.Errorf("ParseBudgets()[%d] = %v, want %v", i, budgets[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{"weekly=1metis", "daily=1eth", "daily=-1usd", "daily", "daily=1metis,24h=2metis"} {
+		if _, err := ParseBudgets(spec); err == nil {
+			t.Errorf("ParseBudgets(%q) = nil error, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit bounds the drips of an address in a rolling window, it's disabled if MaxDrips is 0
+type RateLimit struct {
+	MaxDrips int
+	Window   time.Duration
+}
+
+func (r RateLimit) Enabled() bool {
+	return r.MaxDrips > 0 && r.Window > 0
+}
+
+// ParseRateLimit parses the rate limit in the form of <max drips>/<window>, e.g. "3/24h", the empty one is disabled
+func ParseRateLimit(spec string) (RateLimit, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return RateLimit{}, nil
+	}
+	rawMax, rawWindow, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q", spec)
+	}
+	maxDrips, err := strconv.Atoi(strings.TrimSpace(rawMax))
+	if err != nil || maxDrips <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit count %q", spec)
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(rawWindow))
+	if err != nil || window <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit window %q", spec)
+	}
+	return RateLimit{MaxDrips: maxDrips, Window: window}, nil
+}
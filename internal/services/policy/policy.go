@@ -19,6 +19,14 @@ type Drip struct {
 	EndTime      time.Time
 	MinUSDEqual  float64
 	RebateType   RebateType
+	// the deposits are skipped once any budget of the policy is exhausted
+	Budgets []Budget
+	// the max drips of a layer2 recipient and of a layer1 sender in the rolling windows
+	RecipientLimit RateLimit
+	SenderLimit    RateLimit
+	// the deposits with higher sybil risk scores, or from the layer1 senders funding more recipients, are ignored
+	MaxRiskScore           uint8
+	MaxRecipientsPerSender int
 }
 
 func (d *Drip) Match(time time.Time, token string) bool {
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/islishude/bigint"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// the max layer2 blocks to scan the relays in one log query
+	relayRange = 2000
+	// the relays older than it are not matched with deposits any more
+	relayMatchWindow = time.Hour * 24 * 7
+)
+
+// SyncRelays scans the deposits finalized by the layer2 bridge and links them to the layer1 deposits,
+// only the deposits relayed to layer2 successfully are processed by the faucet.
+func (s *Faucet) SyncRelays(basectx context.Context) error {
+	newctx, cancel := context.WithTimeout(basectx, time.Minute*5)
+	defer cancel()
+
+	latest, err := s.MetisClient.BlockNumber(newctx)
+	if err != nil {
+		return fmt.Errorf("SyncRelays: get latest height: %w", err)
+	}
+
+	for s.relayHeight <= latest {
+		end := min(s.relayHeight+relayRange-1, latest)
+		relays, err := s.filterRelays(&bind.FilterOpts{Start: s.relayHeight, End: &end, Context: newctx})
+		if err != nil {
+			return fmt.Errorf("SyncRelays: %w", err)
+		}
+		if err := s.Repositroy.SaveRelays(newctx, s.L2ChainId, relays, end); err != nil {
+			return fmt.Errorf("SyncRelays: %w", err)
+		}
+		if len(relays) > 0 {
+			logrus.Infof("Relays synced: Range %d-%d Count %d", s.relayHeight, end, len(relays))
+		}
+		s.relayHeight = end + 1
+	}
+
+	matched, err := s.Repositroy.MatchRelays(newctx, s.L2ChainId, time.Now().Add(-relayMatchWindow))
+	if err != nil {
+		return fmt.Errorf("SyncRelays: %w", err)
+	}
+	ignored, err := s.Repositroy.IgnoreFailedRelays(newctx, s.L2ChainId)
+	if err != nil {
+		return fmt.Errorf("SyncRelays: %w", err)
+	}
+	if matched > 0 || ignored > 0 {
+		logrus.Infof("Relays matched: Matched %d FailedDeposits %d", matched, ignored)
+	}
+
+	// the dry-run faucet never changes the deposit status
+	if s.RelayTimeout > 0 && s.DryRun == "" {
+		unrelayed, err := s.Repositroy.IgnoreUnrelayedDeposits(newctx, s.L2ChainId, time.Now().Add(-s.RelayTimeout))
+		if err != nil {
+			return fmt.Errorf("SyncRelays: %w", err)
+		}
+		if unrelayed > 0 {
+			logrus.Warnf("Relays timeout: %d deposits are not relayed in %s and ignored", unrelayed, s.RelayTimeout)
+		}
+	}
+	return nil
+}
+
+// initRelayHeight returns the next layer2 height to scan the relays.
+// If it's not initialized and -l2-start-block is not provided, it starts from the relay window of the oldest pending deposit,
+// or the latest height if there is none.
+func (s *Faucet) initRelayHeight(basectx context.Context) (uint64, error) {
+	newctx, cancel := context.WithTimeout(basectx, time.Minute)
+	defer cancel()
+
+	height, ok, err := s.Repositroy.GetRelayHeight(newctx, s.L2ChainId)
+	if err != nil {
+		return 0, fmt.Errorf("initRelayHeight: %w", err)
+	}
+	if ok {
+		return height + 1, nil
+	}
+
+	start := s.RelayStartBlock
+	if start == 0 {
+		latest, err := s.MetisClient.BlockNumber(newctx)
+		if err != nil {
+			return 0, fmt.Errorf("initRelayHeight: get latest height: %w", err)
+		}
+		oldest, ok, err := s.Repositroy.GetOldestUnrelayedDeposit(newctx, s.L2ChainId)
+		if err != nil {
+			return 0, fmt.Errorf("initRelayHeight: %w", err)
+		}
+		start = latest
+		if ok {
+			since := oldest
+			if window := time.Now().Add(-relayMatchWindow); since.Before(window) {
+				since = window
+			}
+			if start, err = searchHeightByTime(newctx, latest, since, s.l2BlockTime); err != nil {
+				return 0, fmt.Errorf("initRelayHeight: %w", err)
+			}
+			logrus.Infof("Relays start from %d for the pending deposits since %s", start, since)
+		}
+	}
+
+	if height, err = s.Repositroy.InitRelayHeight(newctx, s.L2ChainId, start); err != nil {
+		return 0, fmt.Errorf("initRelayHeight: %w", err)
+	}
+	return height, nil
+}
+
+func (s *Faucet) l2BlockTime(ctx context.Context, height uint64) (time.Time, error) {
+	header, err := s.MetisClient.HeaderByNumber(ctx, new(big.Int).SetUint64(height))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get header %d: %w", height, err)
+	}
+	return time.Unix(int64(header.Time), 0).UTC(), nil
+}
+
+// searchHeightByTime returns the lowest height not higher than the latest one whose block time is not before the given time
+func searchHeightByTime(ctx context.Context, latest uint64, since time.Time, blockTime func(context.Context, uint64) (time.Time, error)) (uint64, error) {
+	low, high := uint64(0), latest
+	for low < high {
+		mid := low + (high-low)/2
+		t, err := blockTime(ctx, mid)
+		if err != nil {
+			return 0, fmt.Errorf("searchHeightByTime: %w", err)
+		}
+		if t.Before(since) {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	return low, nil
+}
+
+func (s *Faucet) filterRelays(filterOption *bind.FilterOpts) ([]*repository.Relay, error) {
+	var relays []*repository.Relay
+
+	finalized, err := s.l2Bridge.FilterDepositFinalized(filterOption, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filterRelays: finalized: %w", err)
+	}
+	defer finalized.Close()
+	for finalized.Next() {
+		event := finalized.Event
+		relays = append(relays, formatRelayEvent(event.Raw, event.L1Token.Hex(), event.L2Token.Hex(), event.From.Hex(), event.To.Hex(), event.Amount, false))
+	}
+	if err := finalized.Error(); err != nil {
+		return nil, fmt.Errorf("filterRelays: finalized: %w", err)
+	}
+
+	failed, err := s.l2Bridge.FilterDepositFailed(filterOption, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filterRelays: failed: %w", err)
+	}
+	defer failed.Close()
+	for failed.Next() {
+		event := failed.Event
+		relays = append(relays, formatRelayEvent(event.Raw, event.L1Token.Hex(), event.L2Token.Hex(), event.From.Hex(), event.To.Hex(), event.Amount, true))
+	}
+	if err := failed.Error(); err != nil {
+		return nil, fmt.Errorf("filterRelays: failed: %w", err)
+	}
+
+	if err := s.fillRelayTime(filterOption.Context, relays); err != nil {
+		return nil, fmt.Errorf("filterRelays: %w", err)
+	}
+	return relays, nil
+}
+
+func formatRelayEvent(raw types.Log, l1token, l2token, from, to string, amount *big.Int, failed bool) *repository.Relay {
+	return &repository.Relay{
+		Txid:      raw.TxHash.Hex(),
+		LogIndex:  raw.Index,
+		Height:    raw.BlockNumber,
+		BlockTime: time.Unix(int64(raw.BlockTimestamp), 0).UTC(),
+		L1Token:   strings.ToLower(l1token),
+		L2Token:   strings.ToLower(l2token),
+		From:      strings.ToLower(from),
+		To:        strings.ToLower(to),
+		Amount:    bigint.FromBigInt(amount),
+		Failed:    failed,
+	}
+}
+
+// fillRelayTime fills the block time for the relays if the layer2 node doesn't return the block timestamp with logs
+func (s *Faucet) fillRelayTime(ctx context.Context, relays []*repository.Relay) error {
+	cache := make(map[uint64]time.Time)
+	for _, item := range relays {
+		if item.BlockTime.Unix() > 0 {
+			continue
+		}
+		blockTime, ok := cache[item.Height]
+		if !ok {
+			header, err := s.MetisClient.HeaderByNumber(ctx, new(big.Int).SetUint64(item.Height))
+			if err != nil {
+				return fmt.Errorf("fillRelayTime: get header %d: %w", item.Height, err)
+			}
+			blockTime = time.Unix(int64(header.Time), 0).UTC()
+			cache[item.Height] = blockTime
+		}
+		item.BlockTime = blockTime
+	}
+	return nil
+}
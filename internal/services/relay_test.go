@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSearchHeightByTime(t *testing.T) {
+	genesis := time.Unix(1700000000, 0)
+	// a block every 2 seconds
+	blockTime := func(_ context.Context, height uint64) (time.Time, error) {
+		return genesis.Add(time.Duration(height) * time.Second * 2), nil
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  uint64
+	}{
+		{"before genesis", genesis.Add(-time.Hour), 0},
+		{"exact block", genesis.Add(time.Second * 200), 100},
+		{"between blocks", genesis.Add(time.Second * 201), 101},
+		{"after latest", genesis.Add(time.Hour), 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := searchHeightByTime(context.Background(), 1000, tt.since, blockTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("searchHeightByTime() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
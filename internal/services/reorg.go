@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// checkReorg verifies the persisted checkpoints against the canonical chain,
+// and rolls back the synced data to the latest common ancestor if they diverge.
+// The walk is bounded by the checkpoint retention depth, if no checkpoint in it is canonical,
+// the synced data is rolled back to the block below the oldest checkpoint.
+func (s *DataSync) checkReorg(basectx context.Context) error {
+	newctx, cancel := context.WithTimeout(basectx, time.Minute)
+	defer cancel()
+
+	var since uint64
+	if s.height > repository.CheckpointRetention {
+		since = s.height - repository.CheckpointRetention
+	}
+
+	checkpoints, err := s.Repositroy.GetCheckpoints(newctx, since)
+	if err != nil {
+		return fmt.Errorf("checkReorg: %w", err)
+	}
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	idx, err := findCommonAncestor(checkpoints, func(checkpoint *repository.Height) (bool, error) {
+		canonical, err := s.getCanonicalHash(newctx, checkpoint.Number)
+		if err != nil {
+			return false, err
+		}
+		if canonical != checkpoint.Blockhash {
+			logrus.Warnf("Reorg detected: Height %d Stored %s Canonical %s", checkpoint.Number, checkpoint.Blockhash, canonical)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("checkReorg: %w", err)
+	}
+	if idx == 0 {
+		return nil
+	}
+
+	var fork *repository.Height
+	if idx < len(checkpoints) {
+		fork = checkpoints[idx]
+	} else {
+		oldest := checkpoints[len(checkpoints)-1]
+		if oldest.Number == 0 {
+			return fmt.Errorf("checkReorg: the genesis checkpoint is not canonical")
+		}
+		canonical, err := s.getCanonicalHash(newctx, oldest.Number-1)
+		if err != nil {
+			return fmt.Errorf("checkReorg: %w", err)
+		}
+		fork = &repository.Height{Number: oldest.Number - 1, Blockhash: canonical}
+		logrus.Errorf("No common ancestor found in the %d checkpoints since %d, falling back to %d", len(checkpoints), oldest.Number, fork.Number)
+	}
+
+	logrus.Warnf("Rolling back to the common ancestor %d %s", fork.Number, fork.Blockhash)
+	result, err := s.Repositroy.Rollback(newctx, fork)
+	if err != nil {
+		return fmt.Errorf("checkReorg: %w", err)
+	}
+	for _, id := range result.Flagged {
+		logrus.Errorf("Reorged deposit %d has a drip already, please review it", id)
+	}
+	logrus.Warnf("Rollback done: RemovedDeposits %d FlaggedDeposits %d", result.Removed, len(result.Flagged))
+	s.height = fork.Number + 1
+	return nil
+}
+
+func (s *DataSync) getCanonicalHash(ctx context.Context, number uint64) (string, error) {
+	header, err := s.EtherClient.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return "", fmt.Errorf("get header %d: %w", number, err)
+	}
+	return header.Hash().Hex(), nil
+}
+
+// findCommonAncestor returns the index of the latest canonical checkpoint,
+// the checkpoints are in descending order, and the ones above the fork point are not canonical,
+// so the latest one is checked first and the others are bisected.
+// It returns len(checkpoints) if none of them is canonical.
+func findCommonAncestor(checkpoints []*repository.Height, isCanonical func(*repository.Height) (bool, error)) (int, error) {
+	if len(checkpoints) == 0 {
+		return 0, nil
+	}
+
+	ok, err := isCanonical(checkpoints[0])
+	if err != nil || ok {
+		return 0, err
+	}
+
+	low, high := 1, len(checkpoints)
+	for low < high {
+		middle := low + (high-low)/2
+		ok, err := isCanonical(checkpoints[middle])
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+	return low, nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+func TestFindCommonAncestor(t *testing.T) {
+	// a checkpoint every block from 100 down to 1
+	var checkpoints []*repository.Height
+	for number := uint64(100); number > 0; number-- {
+		checkpoints = append(checkpoints, &repository.Height{Number: number})
+	}
+
+	tests := []struct {
+		name string
+		fork uint64
+		want int
+	}{
+		{"no reorg", 100, 0},
+		{"shallow reorg", 99, 1},
+		{"deep reorg", 30, 70},
+		{"oldest is canonical", 1, 99},
+		{"none is canonical", 0, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findCommonAncestor(checkpoints, func(checkpoint *repository.Height) (bool, error) {
+				return checkpoint.Number <= tt.fork, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("findCommonAncestor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		_, err := findCommonAncestor(checkpoints, func(*repository.Height) (bool, error) {
+			return false, errors.New("rpc error")
+		})
+		if err == nil {
+			t.Error("findCommonAncestor() should return the error")
+		}
+	})
+}
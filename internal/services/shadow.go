@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/metis-devops/metis-bridge-rebate/internal/services/policy"
+	"github.com/metis-devops/metis-bridge-rebate/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// shadowDrips runs the eligibility and amount pipeline of the drips in the dry-run mode
+func (s *Faucet) shadowDrips(ctx context.Context) {
+	if err := s.SyncRelays(ctx); err != nil {
+		logrus.Errorf("sync relays: %s", err)
+		return
+	}
+	tokens, err := utils.GetBridgeTokens(ctx)
+	if err != nil {
+		logrus.Errorf("Get supported tokens: %s", err)
+		return
+	}
+	if err := s.tryToShadowDrip(ctx, tokens); err != nil {
+		logrus.Errorf("failed to shadow drips: %s", err)
+	}
+}
+
+func (s *Faucet) tryToShadowDrip(ctx context.Context, bridgeTokens map[string]string) error {
+	recset := make(map[string]bool)
+	budgets := s.newBudgetTracker(time.Now())
+	// the usd values are always recorded to compare the configs
+	tokenInfo, err := s.Uniswap.GetToken(ctx, s.MetisL1Contract)
+	if err != nil {
+		return fmt.Errorf("get metis price: %w", err)
+	}
+	metisUSD := tokenInfo.ValueInUSD
+	for item := range s.Repositroy.GetShadowDepositStream(ctx, s.L2ChainId, s.DryRun) {
+		if item.Error != nil {
+			return item.Error
+		}
+
+		var policy *policy.Drip
+		for _, p := range s.DripPolicies {
+			if p.Match(item.Data.BlockTime, item.Data.L1Token) {
+				policy = p
+			}
+		}
+
+		shadow := &repository.ShadowDrip{Name: s.DryRun, Pid: item.Data.Id}
+		if policy != nil {
+			shadow.Policy = policy.Name
+		}
+		if err := s.shadowDrip(ctx, budgets, metisUSD, policy, item.Data, recset, bridgeTokens, shadow); err != nil {
+			return err
+		}
+		shadow.RiskScore = item.Data.RiskScore
+		if err := s.Repositroy.SaveShadowDrip(ctx, shadow); err != nil {
+			return err
+		}
+		logrus.Infof("Shadow drip %s: Decision %s Amount %f Metis %f USD Reason %q",
+			item.Data.Txid, shadow.Decision, shadow.Amount, shadow.USD, shadow.Reason)
+	}
+	return nil
+}
+
+// shadowDrip fills the decision of the deposit, it returns an error only if the decision can't be made
+func (s *Faucet) shadowDrip(ctx context.Context, budgets *budgetTracker, metisUSD float64, pc *policy.Drip,
+	item *repository.Deposit, recset map[string]bool, bridgeTokens map[string]string, shadow *repository.ShadowDrip) error {
+	if err := s.shouldTransfer(ctx, pc, item, recset, bridgeTokens, nil); err != nil {
+		v, ok := err.(ErrorNoNeedToTransfer)
+		if !ok {
+			return err
+		}
+		return s.shadowReject(ctx, item, v, shadow)
+	}
+
+	dripAmount, err := s.calMetisDrip(ctx, pc, item.Txid)
+	if err != nil {
+		return err
+	}
+	metis := utils.ToEther(dripAmount)
+	usd := metis * metisUSD
+	shadow.Amount, shadow.USD = metis, usd
+
+	if err := s.checkBudgets(ctx, budgets, pc, metis, usd); err != nil {
+		v, ok := err.(ErrorBudgetExhausted)
+		if !ok {
+			return err
+		}
+		shadow.Decision, shadow.Reason = repository.DecisionSkip, v.msg
+		return nil
+	}
+	s.spendBudgets(budgets, pc, metis, usd)
+	recset[item.To] = true
+	shadow.Decision = repository.DecisionDrip
+	return nil
+}
+
+// shadowReject defers the deposit rejected for a transient reason like the live faucet,
+// the attempts are counted by the shadow run as it never changes the deposit.
+func (s *Faucet) shadowReject(ctx context.Context, item *repository.Deposit, rejection ErrorNoNeedToTransfer, shadow *repository.ShadowDrip) error {
+	msg := rejection.msg
+	if rejection.Transient() {
+		prev, err := s.Repositroy.GetShadowDrip(ctx, s.DryRun, item.Id)
+		if err != nil {
+			return err
+		}
+		deposit := *item
+		deposit.Attempts = 0
+		if prev != nil && prev.Decision == repository.DecisionDefer {
+			deposit.Attempts = prev.Attempts
+		}
+		if next, ok := s.deferUntil(&deposit, rejection, time.Now()); ok {
+			shadow.Decision, shadow.Reason = repository.DecisionDefer, msg
+			shadow.Attempts, shadow.NextRetry = deposit.Attempts+1, &next
+			return nil
+		}
+		if deposit.Attempts > 0 {
+			msg = fmt.Sprintf("%s, expired after %d deferrals", msg, deposit.Attempts)
+		}
+	}
+	shadow.Decision, shadow.Reason = repository.DecisionIgnore, msg
+	return nil
+}
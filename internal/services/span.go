@@ -0,0 +1,82 @@
+package services
+
+import (
+	"strings"
+	"sync"
+)
+
+// the consecutive successes required to double the range span
+const spanGrowAfter = 10
+
+// rangeSpan learns the max block span the rpc provider accepts for a log query
+type rangeSpan struct {
+	mu        sync.Mutex
+	current   uint64
+	max       uint64
+	successes int
+}
+
+func newRangeSpan(current, max uint64) *rangeSpan {
+	if current == 0 || current > max {
+		current = max
+	}
+	return &rangeSpan{current: current, max: max}
+}
+
+func (r *rangeSpan) get() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// shrink halves the span after a range with the given span is rejected, it returns true if the span is changed
+func (r *rangeSpan) shrink(rejected uint64) (uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.successes = 0
+	if span := max(rejected/2, 1); span < r.current {
+		r.current = span
+		return r.current, true
+	}
+	return r.current, false
+}
+
+// succeed doubles the span after enough consecutive successes, it returns true if the span is changed
+func (r *rangeSpan) succeed(span uint64) (uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if span < r.current || r.current >= r.max {
+		return r.current, false
+	}
+	if r.successes++; r.successes < spanGrowAfter {
+		return r.current, false
+	}
+	r.successes = 0
+	r.current = min(r.current*2, r.max)
+	return r.current, true
+}
+
+// isRangeTooLargeError reports whether the rpc provider rejects a log query for its block span or result count
+func isRangeTooLargeError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, keyword := range []string{
+		"query returned more than",
+		"range too large",
+		"range is too large",
+		"range is too wide",
+		"exceed maximum block range",
+		"response size exceeded",
+		"response size should not greater than",
+		"too many blocks",
+		"too many results",
+		"block range limit",
+	} {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRangeSpan(t *testing.T) {
+	span := newRangeSpan(0, 1000)
+	if got := span.get(); got != 1000 {
+		t.Fatalf("get() = %d, want 1000", got)
+	}
+
+	if got, changed := span.shrink(1000); !changed || got != 500 {
+		t.Errorf("shrink() = %d %v, want 500 true", got, changed)
+	}
+	// an in-flight range with the old span should not shrink it twice
+	if got, changed := span.shrink(1000); changed || got != 500 {
+		t.Errorf("shrink() = %d %v, want 500 false", got, changed)
+	}
+
+	for i := 1; i < spanGrowAfter; i++ {
+		if _, changed := span.succeed(500); changed {
+			t.Fatalf("succeed() should not grow before %d successes", spanGrowAfter)
+		}
+	}
+	if got, changed := span.succeed(500); !changed || got != 1000 {
+		t.Errorf("succeed() = %d %v, want 1000 true", got, changed)
+	}
+}
+
+func TestIsRangeTooLargeError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"infura", errors.New("query returned more than 10000 results"), true},
+		{"alchemy", errors.New("Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"), true},
+		{"block range", errors.New("block range is too wide"), true},
+		{"timeout", errors.New("context deadline exceeded"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRangeTooLargeError(tt.err); got != tt.want {
+				t.Errorf("isRangeTooLargeError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metis-devops/metis-bridge-rebate/internal/goabi"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// Stream syncs deposits in real time with the websocket subscriptions, it requires a websocket EtherClient.
+// The gap since the last synced height is filled with range filtering after every (re)connection.
+func (s *DataSync) Stream(basectx context.Context) {
+	for {
+		if err := s.tryToStream(basectx); err != nil && !errors.Is(err, context.Canceled) {
+			logrus.Errorf("stream fail: %s", err)
+		}
+		select {
+		case <-basectx.Done():
+			return
+		case <-time.After(time.Second * 10):
+		}
+	}
+}
+
+type eventKey struct {
+	txid     string
+	logIndex uint
+}
+
+// pendingEvents buffers the subscribed events until they reach the confirmation depth
+type pendingEvents struct {
+	deposits    map[eventKey]*repository.Deposit
+	withdrawals map[eventKey]*repository.Withdrawal
+	chainIDs    erc20ChainIDs
+}
+
+func newPendingEvents() *pendingEvents {
+	return &pendingEvents{
+		deposits:    make(map[eventKey]*repository.Deposit),
+		withdrawals: make(map[eventKey]*repository.Withdrawal),
+		chainIDs:    make(erc20ChainIDs),
+	}
+}
+
+func (p *pendingEvents) addDeposit(dpt *repository.Deposit, removed bool) {
+	key := eventKey{txid: dpt.Txid, logIndex: dpt.LogIndex}
+	if removed {
+		delete(p.deposits, key)
+		return
+	}
+	p.deposits[key] = dpt
+}
+
+func (p *pendingEvents) addWithdrawal(wd *repository.Withdrawal, removed bool) {
+	key := eventKey{txid: wd.Txid, logIndex: wd.LogIndex}
+	if removed {
+		delete(p.withdrawals, key)
+		return
+	}
+	p.withdrawals[key] = wd
+}
+
+// pop removes and returns the buffered events in the given range, the ones lower than the range are dropped
+func (p *pendingEvents) pop(startHeight, endHeight, defaultChainId uint64) *rangeEvents {
+	var res = new(rangeEvents)
+	for key, dpt := range p.deposits {
+		if dpt.Height <= endHeight {
+			if dpt.Height >= startHeight {
+				res.deposits = append(res.deposits, dpt)
+			}
+			delete(p.deposits, key)
+		}
+	}
+	for key, wd := range p.withdrawals {
+		if wd.Height <= endHeight {
+			if wd.Height >= startHeight {
+				res.withdrawals = append(res.withdrawals, wd)
+			}
+			delete(p.withdrawals, key)
+		}
+	}
+	p.chainIDs.assign(res.deposits, defaultChainId)
+	p.chainIDs.prune(endHeight)
+
+	sort.Slice(res.deposits, func(i, j int) bool {
+		if res.deposits[i].Height != res.deposits[j].Height {
+			return res.deposits[i].Height < res.deposits[j].Height
+		}
+		return res.deposits[i].LogIndex < res.deposits[j].LogIndex
+	})
+	sort.Slice(res.withdrawals, func(i, j int) bool {
+		if res.withdrawals[i].Height != res.withdrawals[j].Height {
+			return res.withdrawals[i].Height < res.withdrawals[j].Height
+		}
+		return res.withdrawals[i].LogIndex < res.withdrawals[j].LogIndex
+	})
+	return res
+}
+
+func (s *DataSync) tryToStream(basectx context.Context) error {
+	ctx, cancel := context.WithCancel(basectx)
+	defer cancel()
+
+	heads := make(chan *types.Header, 16)
+	headSub, err := s.EtherClient.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return fmt.Errorf("tryToStream: subscribe new head: %w", err)
+	}
+	defer headSub.Unsubscribe()
+
+	watchOption := &bind.WatchOpts{Context: ctx}
+	erc20Events := make(chan *goabi.L1StandardBridgeERC20DepositInitiated, 64)
+	erc20Sub, err := s.Bridge.WatchERC20DepositInitiated(watchOption, erc20Events, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("tryToStream: watch erc20 deposit event: %w", err)
+	}
+	defer erc20Sub.Unsubscribe()
+
+	etherEvents := make(chan *goabi.L1StandardBridgeETHDepositInitiated, 64)
+	etherSub, err := s.Bridge.WatchETHDepositInitiated(watchOption, etherEvents, nil, nil)
+	if err != nil {
+		return fmt.Errorf("tryToStream: watch ether deposit event: %w", err)
+	}
+	defer etherSub.Unsubscribe()
+
+	erc20WithdrawalEvents := make(chan *goabi.L1StandardBridgeERC20WithdrawalFinalized, 64)
+	erc20WithdrawalSub, err := s.Bridge.WatchERC20WithdrawalFinalized(watchOption, erc20WithdrawalEvents, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("tryToStream: watch erc20 withdrawal event: %w", err)
+	}
+	defer erc20WithdrawalSub.Unsubscribe()
+
+	etherWithdrawalEvents := make(chan *goabi.L1StandardBridgeETHWithdrawalFinalized, 64)
+	etherWithdrawalSub, err := s.Bridge.WatchETHWithdrawalFinalized(watchOption, etherWithdrawalEvents, nil, nil)
+	if err != nil {
+		return fmt.Errorf("tryToStream: watch ether withdrawal event: %w", err)
+	}
+	defer etherWithdrawalSub.Unsubscribe()
+
+	chainIDEvents := make(chan *goabi.L1StandardBridgeERC20ChainID, 64)
+	chainIDSub, err := s.Bridge.WatchERC20ChainID(watchOption, chainIDEvents)
+	if err != nil {
+		return fmt.Errorf("tryToStream: watch erc20 chain id event: %w", err)
+	}
+	defer chainIDSub.Unsubscribe()
+
+	// the blocks after the subscribed height are guaranteed to be delivered by the subscriptions,
+	// the ones before it have to be filled with range filtering.
+	subscribedHeight, err := func() (uint64, error) {
+		newctx, cancel := context.WithTimeout(ctx, time.Second*10)
+		defer cancel()
+		return s.EtherClient.BlockNumber(newctx)
+	}()
+	if err != nil {
+		return fmt.Errorf("tryToStream: get subscribed height: %w", err)
+	}
+	logrus.Infof("Streaming new events from %d", subscribedHeight+1)
+
+	var (
+		pending = newPendingEvents()
+		filling chan error
+		latest  uint64
+	)
+	defer func() {
+		// wait for the filling to exit before resubscribing
+		if filling != nil {
+			cancel()
+			<-filling
+		}
+	}()
+
+	fillGap := func() {
+		if filling != nil {
+			return
+		}
+		target := min(subscribedHeight, latest)
+		if s.height > target {
+			return
+		}
+		filling = make(chan error, 1)
+		go func(done chan<- error) {
+			done <- s.syncTo(ctx, target)
+		}(filling)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-headSub.Err():
+			return fmt.Errorf("tryToStream: new head subscription: %w", err)
+		case err := <-erc20Sub.Err():
+			return fmt.Errorf("tryToStream: erc20 deposit subscription: %w", err)
+		case err := <-etherSub.Err():
+			return fmt.Errorf("tryToStream: ether deposit subscription: %w", err)
+		case err := <-erc20WithdrawalSub.Err():
+			return fmt.Errorf("tryToStream: erc20 withdrawal subscription: %w", err)
+		case err := <-etherWithdrawalSub.Err():
+			return fmt.Errorf("tryToStream: ether withdrawal subscription: %w", err)
+		case err := <-chainIDSub.Err():
+			return fmt.Errorf("tryToStream: erc20 chain id subscription: %w", err)
+		case event := <-chainIDEvents:
+			pending.chainIDs.add(event, event.Raw.Removed)
+		case event := <-erc20Events:
+			dpt, err := s.formatERC20DepositEvent(event)
+			if err != nil {
+				return err
+			}
+			pending.addDeposit(dpt, event.Raw.Removed)
+		case event := <-etherEvents:
+			dpt, err := s.formatETHDepositEvent(event)
+			if err != nil {
+				return err
+			}
+			pending.addDeposit(dpt, event.Raw.Removed)
+		case event := <-erc20WithdrawalEvents:
+			wd, err := s.formatERC20WithdrawalEvent(event)
+			if err != nil {
+				return err
+			}
+			pending.addWithdrawal(wd, event.Raw.Removed)
+		case event := <-etherWithdrawalEvents:
+			wd, err := s.formatETHWithdrawalEvent(event)
+			if err != nil {
+				return err
+			}
+			pending.addWithdrawal(wd, event.Raw.Removed)
+		case err := <-filling:
+			filling = nil
+			if err != nil {
+				return err
+			}
+		case head := <-heads:
+			confirmed, err := s.getConfirmedHeight(ctx, head.Number.Uint64())
+			if err != nil {
+				return err
+			}
+			latest = confirmed
+			if filling != nil {
+				continue
+			}
+			if s.height <= subscribedHeight {
+				fillGap()
+				continue
+			}
+			if err := s.commitPending(ctx, pending, latest); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// commitPending saves the buffered events which have reached the target height
+func (s *DataSync) commitPending(basectx context.Context, pending *pendingEvents, targetHeight uint64) error {
+	if s.height > targetHeight {
+		return nil
+	}
+
+	height := s.height
+	if err := s.checkReorg(basectx); err != nil {
+		return err
+	}
+	if s.height != height {
+		return errors.New("commitPending: rolled back to the common ancestor, the gap should be filled again")
+	}
+
+	newctx, cancel := context.WithTimeout(basectx, time.Minute)
+	defer cancel()
+
+	header, err := s.EtherClient.HeaderByNumber(newctx, new(big.Int).SetUint64(targetHeight))
+	if err != nil {
+		return fmt.Errorf("commitPending: get tail header: %w", err)
+	}
+
+	// the lower ones have been synced by range filtering
+	events := pending.pop(s.height, targetHeight, s.defaultChainId)
+	if err := s.fillBlockTime(newctx, events); err != nil {
+		return fmt.Errorf("commitPending: %w", err)
+	}
+	if err := s.saveRange(newctx, &syncedRange{start: s.height, end: targetHeight, tail: header, events: events}); err != nil {
+		return fmt.Errorf("commitPending: %w", err)
+	}
+	s.height = targetHeight + 1
+	return nil
+}
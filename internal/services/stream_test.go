@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+func TestPendingEvents(t *testing.T) {
+	pending := newPendingEvents()
+	pending.addDeposit(&repository.Deposit{Txid: "0x02", LogIndex: 1, Height: 12}, false)
+	pending.addDeposit(&repository.Deposit{Txid: "0x01", LogIndex: 3, Height: 10}, false)
+	pending.addDeposit(&repository.Deposit{Txid: "0x01", LogIndex: 2, Height: 10}, false)
+	pending.addDeposit(&repository.Deposit{Txid: "0x03", LogIndex: 0, Height: 11}, false)
+	pending.addDeposit(&repository.Deposit{Txid: "0x03", LogIndex: 0, Height: 11}, true)
+
+	pending.addWithdrawal(&repository.Withdrawal{Txid: "0x00", LogIndex: 0, Height: 9}, false)
+	pending.addWithdrawal(&repository.Withdrawal{Txid: "0x04", LogIndex: 0, Height: 11}, false)
+
+	events := pending.pop(10, 11, 1088)
+	if len(events.withdrawals) != 1 || events.withdrawals[0].Txid != "0x04" {
+		t.Errorf("pop() should drop the withdrawals lower than the range")
+	}
+
+	got := events.deposits
+	if len(got) != 2 {
+		t.Fatalf("pop() length = %d, want 2", len(got))
+	}
+	if got[0].LogIndex != 2 || got[1].LogIndex != 3 {
+		t.Errorf("pop() should be sorted by height and log index")
+	}
+	if len(pending.deposits) != 1 || len(pending.withdrawals) != 0 {
+		t.Errorf("pending length = %d %d, want 1 0", len(pending.deposits), len(pending.withdrawals))
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/metis-devops/metis-bridge-rebate/internal/services/policy"
+)
+
+const (
+	// the deposits around the block time in it are clustered by the layer1 sender
+	sybilWindow = time.Hour * 24 * 7
+	// the deposits around the block time in it are treated as sent by one script
+	sybilBurst = time.Minute * 10
+)
+
+// sybilScore weighs the clustering signals into a risk score from 0 to 100, the first recipient of a sender scores 0
+func sybilScore(signals *repository.SybilSignals) uint8 {
+	var score int
+	// one sender funding many recipients
+	score += min(max(signals.SenderRecipients-1, 0)*10, 50)
+	// and funding them in a burst
+	score += min(max(signals.BurstRecipients-1, 0)*15, 30)
+	// many senders depositing the identical amount at the same time
+	score += min(max(signals.SameAmountSenders-1, 0)*5, 20)
+	// many senders funded by the same account
+	score += min(max(signals.FunderSenders-1, 0)*10, 30)
+	return uint8(min(score, 100))
+}
+
+// checkSybil scores the deposit once and saves the score, it rejects the deposit if it exceeds the policy limits
+func (s *Faucet) checkSybil(ctx context.Context, pc *policy.Drip, item *repository.Deposit, rec *decisionRecorder) error {
+	var senderRecipients = -1
+	if item.RiskScore == nil {
+		if s.TraceFunder {
+			if _, err := s.getFunder(ctx, item); err != nil {
+				return err
+			}
+		}
+		signals, err := s.Repositroy.GetSybilSignals(ctx, item, sybilWindow, sybilBurst)
+		if err != nil {
+			return err
+		}
+		score := sybilScore(signals)
+		// the dry-run faucet records the score in the shadow table
+		if s.DryRun == "" {
+			if err := s.Repositroy.SetRiskScore(ctx, item.Id, score); err != nil {
+				return err
+			}
+		}
+		item.RiskScore = &score
+		senderRecipients = signals.SenderRecipients
+	}
+	score := *item.RiskScore
+
+	if pc.MaxRecipientsPerSender > 0 {
+		// the deposit scored in an earlier loop only counts the recipients again
+		if senderRecipients < 0 {
+			count, err := s.Repositroy.CountSenderRecipients(ctx, item, sybilWindow)
+			if err != nil {
+				return err
+			}
+			senderRecipients = count
+		}
+		if senderRecipients > pc.MaxRecipientsPerSender {
+			return ErrorNoNeedToTransfer{code: ReasonSybilRecipients, msg: fmt.Sprintf("sender %s funds %d recipients > Max %d", item.From, senderRecipients, pc.MaxRecipientsPerSender)}
+		}
+	}
+	if pc.MaxRiskScore > 0 && score > pc.MaxRiskScore {
+		return ErrorNoNeedToTransfer{code: ReasonSybilScore, msg: fmt.Sprintf("risk score %d > Max %d", score, pc.MaxRiskScore)}
+	}
+	detail := fmt.Sprintf("score %d", score)
+	if senderRecipients >= 0 {
+		detail += fmt.Sprintf(", sender recipients %d", senderRecipients)
+	}
+	rec.pass(checkSybil, detail)
+	return nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+)
+
+func TestSybilScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		signals repository.SybilSignals
+		want    uint8
+	}{
+		{"single deposit", repository.SybilSignals{SenderRecipients: 1, BurstRecipients: 1, SameAmountSenders: 1}, 0},
+		{"a few recipients over the week", repository.SybilSignals{SenderRecipients: 3, BurstRecipients: 1, SameAmountSenders: 1}, 20},
+		{"recipients in a burst", repository.SybilSignals{SenderRecipients: 3, BurstRecipients: 3, SameAmountSenders: 1}, 50},
+		{"identical amounts of many senders", repository.SybilSignals{SenderRecipients: 1, BurstRecipients: 1, SameAmountSenders: 4}, 15},
+		{"senders of one funder", repository.SybilSignals{SenderRecipients: 1, BurstRecipients: 1, SameAmountSenders: 1, FunderSenders: 3}, 20},
+		{"senders of a busy funder", repository.SybilSignals{SenderRecipients: 1, BurstRecipients: 1, SameAmountSenders: 1, FunderSenders: 100}, 30},
+		{"farm", repository.SybilSignals{SenderRecipients: 200, BurstRecipients: 50, SameAmountSenders: 30}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sybilScore(&tt.signals); got != tt.want {
+				t.Errorf("sybilScore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/metis-devops/metis-bridge-rebate/internal/signer"
+	"github.com/metis-devops/metis-bridge-rebate/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// the hard floor of a wallet balance to send new drips
+const minWalletBalance = 0.5
+
+// Wallet is a hot wallet of the faucet, every wallet has its own nonce sequence
+type Wallet struct {
+	Signer  signer.Signer
+	Account common.Address
+	// the wallet doesn't send new drips if its balance is lower than it, the faucet ReservedBalance is used if it's 0
+	MinBalance float64
+
+	nonce nonceTracker
+}
+
+func (w *Wallet) sign(ctx context.Context, chainSigner types.Signer, data types.TxData) (*types.Transaction, error) {
+	return w.Signer.SignTx(ctx, types.NewTx(data), chainSigner.ChainID())
+}
+
+func (s *Faucet) walletThreshold(w *Wallet) float64 {
+	threshold := s.ReservedBalance
+	if w.MinBalance > 0 {
+		threshold = w.MinBalance
+	}
+	return max(threshold, minWalletBalance)
+}
+
+// getHealthyWallets returns the wallets whose balances are above their thresholds
+func (s *Faucet) getHealthyWallets(basectx context.Context) ([]*Wallet, error) {
+	newctx, cancel := context.WithTimeout(basectx, time.Second*10)
+	defer cancel()
+
+	var healthy []*Wallet
+	for _, w := range s.Wallets {
+		balance, err := s.MetisClient.BalanceAt(newctx, w.Account, nil)
+		if err != nil {
+			return nil, fmt.Errorf("getHealthyWallets: get balance of %s: %w", w.Account, err)
+		}
+		if m, threshold := utils.ToEther(balance), s.walletThreshold(w); m < threshold {
+			logrus.Warnf("Wallet %s is skipped: insufficient balance %f < %f Metis", w.Account, m, threshold)
+			continue
+		}
+		healthy = append(healthy, w)
+	}
+	if len(healthy) == 0 {
+		return nil, errors.New("getHealthyWallets: no wallet has sufficient balance")
+	}
+	return healthy, nil
+}
+
+// pickWallet assigns the drips across the healthy wallets in turn
+func (s *Faucet) pickWallet(healthy []*Wallet) *Wallet {
+	w := healthy[s.walletIdx%len(healthy)]
+	s.walletIdx++
+	return w
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/islishude/bigint"
+	"github.com/metis-devops/metis-bridge-rebate/internal/goabi"
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/metis-devops/metis-bridge-rebate/internal/utils"
+)
+
+// formatERC20WithdrawalEvent builds the erc20 withdrawal, the event has no chain id so it comes from the default chain
+func (s *DataSync) formatERC20WithdrawalEvent(event *goabi.L1StandardBridgeERC20WithdrawalFinalized) (*repository.Withdrawal, error) {
+	return &repository.Withdrawal{
+		Height:    event.Raw.BlockNumber,
+		Blockhash: event.Raw.BlockHash.Hex(),
+		BlockTime: time.Unix(int64(event.Raw.BlockTimestamp), 0).UTC(),
+		Txid:      event.Raw.TxHash.Hex(),
+		LogIndex:  event.Raw.Index,
+		ChainId:   s.defaultChainId,
+		L1Token:   strings.ToLower(event.L1Token.Hex()),
+		L2Token:   strings.ToLower(event.L2Token.Hex()),
+		From:      strings.ToLower(event.From.Hex()),
+		To:        strings.ToLower(event.To.Hex()),
+		Amount:    bigint.FromBigInt(event.Amount),
+	}, nil
+}
+
+func (s *DataSync) formatETHWithdrawalEvent(event *goabi.L1StandardBridgeETHWithdrawalFinalized) (*repository.Withdrawal, error) {
+	var chainId = s.defaultChainId
+	if event.ChainId != nil && event.ChainId.Sign() > 0 {
+		chainId = event.ChainId.Uint64()
+	}
+
+	return &repository.Withdrawal{
+		Height:    event.Raw.BlockNumber,
+		Blockhash: event.Raw.BlockHash.Hex(),
+		BlockTime: time.Unix(int64(event.Raw.BlockTimestamp), 0).UTC(),
+		Txid:      event.Raw.TxHash.Hex(),
+		LogIndex:  event.Raw.Index,
+		ChainId:   chainId,
+		L1Token:   strings.ToLower(utils.EtherL1Address),
+		L2Token:   strings.ToLower(utils.EtherL2Address),
+		From:      strings.ToLower(event.From.Hex()),
+		To:        strings.ToLower(event.To.Hex()),
+		Amount:    bigint.FromBigInt(event.Amount),
+	}, nil
+}
+
+func (s *DataSync) filterERC20Withdrawals(filterOption *bind.FilterOpts) ([]*repository.Withdrawal, error) {
+	iter, err := s.Bridge.FilterERC20WithdrawalFinalized(filterOption, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filterERC20Withdrawals: %w", err)
+	}
+	defer iter.Close()
+
+	var withdrawals []*repository.Withdrawal
+	for iter.Next() {
+		wd, err := s.formatERC20WithdrawalEvent(iter.Event)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, wd)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("filterERC20Withdrawals: %w", err)
+	}
+	return withdrawals, nil
+}
+
+func (s *DataSync) filterETHWithdrawals(filterOption *bind.FilterOpts) ([]*repository.Withdrawal, error) {
+	iter, err := s.Bridge.FilterETHWithdrawalFinalized(filterOption, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filterETHWithdrawals: %w", err)
+	}
+	defer iter.Close()
+
+	var withdrawals []*repository.Withdrawal
+	for iter.Next() {
+		wd, err := s.formatETHWithdrawalEvent(iter.Event)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, wd)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("filterETHWithdrawals: %w", err)
+	}
+	return withdrawals, nil
+}
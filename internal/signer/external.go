@@ -0,0 +1,124 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// External delegates the signing to a remote signer with the eth_signTransaction api, e.g. web3signer or clef
+type External struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+func NewExternal(ctx context.Context, endpoint string, address common.Address) (*External, error) {
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("NewExternal: dial %s: %w", endpoint, err)
+	}
+	return &External{client: client, address: address}, nil
+}
+
+func (e *External) Address() common.Address {
+	return e.address
+}
+
+// TxArgs is the eth_signTransaction parameter
+type TxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainId              *hexutil.Big    `json:"chainId"`
+}
+
+func newTxArgs(from common.Address, tx *types.Transaction, chainId *big.Int) *TxArgs {
+	args := &TxArgs{
+		From:    from,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainId: (*hexutil.Big)(chainId),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+	return args
+}
+
+func (e *External) SignTx(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	var result json.RawMessage
+	if err := e.client.CallContext(ctx, &result, "eth_signTransaction", newTxArgs(e.address, tx, chainId)); err != nil {
+		return nil, fmt.Errorf("SignTx: %w", err)
+	}
+
+	rawtx, err := decodeSignResult(result)
+	if err != nil {
+		return nil, fmt.Errorf("SignTx: %w", err)
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(rawtx); err != nil {
+		return nil, fmt.Errorf("SignTx: decode signed tx: %w", err)
+	}
+
+	// never trust the remote to sign what we asked for
+	sender, err := types.Sender(types.LatestSignerForChainID(chainId), signed)
+	if err != nil {
+		return nil, fmt.Errorf("SignTx: recover sender: %w", err)
+	}
+	if sender != e.address {
+		return nil, fmt.Errorf("SignTx: signed by %s, expected %s", sender, e.address)
+	}
+	if !sameTx(tx, signed) {
+		return nil, errors.New("SignTx: the signed tx doesn't match the request")
+	}
+	return signed, nil
+}
+
+// decodeSignResult accepts the raw tx hex of web3signer or the {raw, tx} object of geth and clef
+func decodeSignResult(result json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+	var obj struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &obj); err != nil {
+		return nil, fmt.Errorf("decode sign result: %w", err)
+	}
+	if len(obj.Raw) == 0 {
+		return nil, errors.New("decode sign result: no raw tx")
+	}
+	return obj.Raw, nil
+}
+
+func sameTx(a, b *types.Transaction) bool {
+	if a.Type() != b.Type() || a.Nonce() != b.Nonce() || a.Gas() != b.Gas() ||
+		a.Value().Cmp(b.Value()) != 0 || a.GasFeeCap().Cmp(b.GasFeeCap()) != 0 ||
+		a.GasTipCap().Cmp(b.GasTipCap()) != 0 || string(a.Data()) != string(b.Data()) {
+		return false
+	}
+	if a.To() == nil || b.To() == nil {
+		return a.To() == b.To()
+	}
+	return *a.To() == *b.To()
+}
@@ -0,0 +1,94 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// remoteSigner serves eth_signTransaction with a local signer, tamper changes the tx before signing
+type remoteSigner struct {
+	local  *Local
+	tamper func(*types.DynamicFeeTx)
+}
+
+func (r *remoteSigner) SignTransaction(ctx context.Context, args TxArgs) (hexutil.Bytes, error) {
+	data := &types.DynamicFeeTx{
+		ChainID:   args.ChainId.ToInt(),
+		Nonce:     uint64(args.Nonce),
+		GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: args.MaxFeePerGas.ToInt(),
+		Gas:       uint64(args.Gas),
+		To:        args.To,
+		Value:     args.Value.ToInt(),
+		Data:      args.Data,
+	}
+	if r.tamper != nil {
+		r.tamper(data)
+	}
+	tx, err := r.local.SignTx(ctx, types.NewTx(data), args.ChainId.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	return tx.MarshalBinary()
+}
+
+func TestExternal_SignTx(t *testing.T) {
+	prvkey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := NewLocal(prvkey)
+	chainId := big.NewInt(1088)
+	receiver := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainId,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(3e9),
+		Gas:       21000,
+		To:        &receiver,
+		Value:     big.NewInt(1e17),
+	})
+
+	tests := []struct {
+		name    string
+		address common.Address
+		tamper  func(*types.DynamicFeeTx)
+		wantErr bool
+	}{
+		{"signed", local.Address(), nil, false},
+		{"wrong account", common.HexToAddress("0x01"), nil, true},
+		{"tampered value", local.Address(), func(data *types.DynamicFeeTx) { data.Value = big.NewInt(1e18) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := rpc.NewServer()
+			if err := server.RegisterName("eth", &remoteSigner{local: local, tamper: tt.tamper}); err != nil {
+				t.Fatal(err)
+			}
+			httpserver := httptest.NewServer(server)
+			defer httpserver.Close()
+			defer server.Stop()
+
+			external, err := NewExternal(context.Background(), httpserver.URL, tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := external.SignTx(context.Background(), tx, chainId)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SignTx() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && signed.Hash() == tx.Hash() {
+				t.Errorf("SignTx() returned an unsigned tx")
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs the transactions of an account, the key may live outside of the service
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error)
+}
+
+// Local signs the transactions with an in-memory private key,
+// it's used for the raw and keystore keys, and can stand in for the external signer in tests.
+type Local struct {
+	prvkey  *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewLocal(prvkey *ecdsa.PrivateKey) *Local {
+	return &Local{prvkey: prvkey, address: crypto.PubkeyToAddress(prvkey.PublicKey)}
+}
+
+func (l *Local) Address() common.Address {
+	return l.address
+}
+
+func (l *Local) SignTx(_ context.Context, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainId), l.prvkey)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
@@ -15,6 +16,8 @@ const (
 	WETH9Adddress  = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
 	EtherL2Address = "0x420000000000000000000000000000000000000a"
 	MetisL2Address = "0xdeaddeaddeaddeaddeaddeaddeaddeaddead0000"
+	// the predeployed standard bridge is same on every layer2 chain
+	MetisL2BridgeAddress = "0x4200000000000000000000000000000000000010"
 )
 
 func IsStableL1Token(u string) bool {
@@ -71,6 +74,33 @@ func ReadPrvkey(keyPath string) (*ecdsa.PrivateKey, common.Address, error) {
 	return prvkey, address, nil
 }
 
+// IsKeystore reports whether the key file is an encrypted keystore json rather than a raw hex key
+func IsKeystore(keyPath string) (bool, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(strings.TrimSpace(string(data)), "{"), nil
+}
+
+// ReadKeystore decrypts the go-ethereum keystore json with the password in the password file
+func ReadKeystore(keyPath, passwordPath string) (*ecdsa.PrivateKey, common.Address, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	password, err := os.ReadFile(passwordPath)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+
+	key, err := keystore.DecryptKey(data, strings.TrimRight(string(password), "\r\n"))
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return key.PrivateKey, key.Address, nil
+}
+
 const uniswapQuery = `
 query tokenHourDatas($startTime: Int!, $address: Bytes!, $weth: Bytes!) {
 	ethPrice: tokenHourDatas(
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/metis-devops/metis-bridge-rebate/internal/goabi"
+	"github.com/metis-devops/metis-bridge-rebate/internal/multirpc"
 	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
 	"github.com/metis-devops/metis-bridge-rebate/internal/services"
 	"github.com/metis-devops/metis-bridge-rebate/internal/services/policy"
@@ -22,43 +26,101 @@ import (
 
 func main() {
 	var (
-		MaxDripUSD    float64
-		MetisEndpoint string
-		EtherEndpoint string
-		MysqlEndpoint string
+		MaxDripUSD      float64
+		MetisEndpoint   string
+		EtherEndpoint   string
+		EtherWsEndpoint string
+		EtherQuorum     int
+		MysqlEndpoint   string
 
 		ConfirmationNumber uint64
+		ConfirmationMode   string
 		RangeSyncNumber    uint64
 		StartFromHeight    uint64
+		RelayStartHeight   uint64
+		RelayTimeout       time.Duration
+		SyncMode           string
+		SyncWorkers        int
 
-		KeyPath    string
-		OpenFaucet bool
+		KeyPath        string
+		KeyPassword    string
+		SignerEndpoint string
+		OpenFaucet     bool
+		OpenSync       bool
 
 		// for the common usage case
 		DripAmount      float64
 		DripHeight      uint64
 		ReservedBalance float64
 
+		FeeMode          string
+		TipMultiplier    float64
+		FeeCapMultiplier float64
+		StuckAge         time.Duration
+		MaxFeeGwei       float64
+		DisperseContract string
+		BatchSize        int
+
+		Budget         string
+		PolicyBudget   string
+		RecipientLimit string
+		SenderLimit    string
+		MaxRiskScore   uint
+		MaxRecipients  int
+		TraceFunder    bool
+
+		DryRun      string
+		DeferExpiry time.Duration
+
 		UniswapEndpoint string
 		UniswapApiKey   string
 		UniswapTimeout  time.Duration
 	)
 
 	flag.Float64Var(&MaxDripUSD, "maxdrip", 250, "max drip usd value")
-	flag.StringVar(&EtherEndpoint, "l1rpc", "https://goerli.infura.io/v3/", "l1 rpc endpoint")
+	flag.StringVar(&EtherEndpoint, "l1rpc", "https://goerli.infura.io/v3/", "l1 rpc endpoints, separated by comma")
+	flag.StringVar(&EtherWsEndpoint, "l1ws", "", "l1 websocket rpc endpoint for the streaming sync mode, uses l1rpc if not provided")
+	flag.IntVar(&EtherQuorum, "l1-quorum", 1, "the l1 rpc endpoints count which must agree on logs and block hashes")
 	flag.StringVar(&MetisEndpoint, "l2rpc", "https://goerli.gateway.metisdevops.link", "l2 rpc endpoint")
 	flag.StringVar(&MysqlEndpoint, "mysql", "root:passwd@tcp(127.0.0.1:3306)/metis?parseTime=true", "mysql endpoint")
 	flag.Uint64Var(&ConfirmationNumber, "confirm", 32, "confirmation number for a new despoit")
+	flag.StringVar(&ConfirmationMode, "confirm-mode", "number", "confirmation mode, number uses the -confirm count, safe or finalized uses the block tag")
 	flag.Uint64Var(&RangeSyncNumber, "range", 50000, "range sync at once")
+	flag.IntVar(&SyncWorkers, "sync-workers", 4, "max ranges to fetch concurrently")
+	flag.StringVar(&SyncMode, "sync-mode", "polling", "deposit sync mode, polling or streaming")
 	//  13627429 mainnet 7501326 goerli
 	flag.Uint64Var(&StartFromHeight, "start-block", 7501326, "initial from height")
 
+	flag.Uint64Var(&RelayStartHeight, "l2-start-block", 0, "initial l2 height to scan the deposit relays, starts from the relay window of the oldest pending deposit if not provided")
+	flag.DurationVar(&RelayTimeout, "relay-timeout", time.Hour*24, "the unprocessed deposits not relayed to l2 within it are ignored, 0 disables it")
+
 	flag.Uint64Var(&DripHeight, "height", 7945105, "height to transfer a drip")
 	flag.Float64Var(&DripAmount, "drip", 0.01, "metis amount to transfer")
 	flag.Float64Var(&ReservedBalance, "reserved", 1, "reserved balance")
+	flag.StringVar(&FeeMode, "fee-mode", "auto", "drip transaction fee mode, auto uses dynamic fee if the l2 supports London, dynamic or legacy forces the type")
+	flag.Float64Var(&TipMultiplier, "tip-multiplier", 1, "multiplier of the suggested priority fee for dynamic fee drips")
+	flag.DurationVar(&StuckAge, "stuck-age", time.Minute*10, "the pending drip older than it is replaced with bumped fees, 0 disables the replacement")
+	flag.Float64Var(&MaxFeeGwei, "max-fee-gwei", 100, "the max fee cap or gas price in gwei the stuck drips are bumped to, 0 disables the cap")
+	flag.Float64Var(&FeeCapMultiplier, "feecap-multiplier", 2, "multiplier of the base fee added to the priority fee as the fee cap for dynamic fee drips")
 
-	flag.StringVar(&KeyPath, "key", "key.txt", "private key path")
+	flag.StringVar(&DisperseContract, "disperse", "", "the disperse contract to send the drips in batches, the batch mode is disabled if not provided")
+	flag.IntVar(&BatchSize, "batch-size", 100, "max drips in a batch")
+
+	flag.StringVar(&Budget, "budget", "", "global drip budgets, e.g. hourly=100metis,daily=2000usd,lifetime=50000metis")
+	flag.StringVar(&PolicyBudget, "policy-budget", "", "drip budgets of the default policy, in the same form of -budget")
+	flag.StringVar(&RecipientLimit, "recipient-limit", "", "max drips of a l2 recipient in a rolling window of the default policy, e.g. 1/24h")
+	flag.UintVar(&MaxRiskScore, "max-risk-score", 0, "max sybil risk score from 0 to 100 of the default policy, 0 disables it")
+	flag.IntVar(&MaxRecipients, "max-recipients-per-sender", 0, "max l2 recipients funded by a l1 sender in a week of the default policy, 0 disables it")
+	flag.BoolVar(&TraceFunder, "trace-funder", false, "trace the l1 funder of a deposit sender for the sybil scoring, it needs an archive l1rpc")
+	flag.StringVar(&SenderLimit, "sender-limit", "", "max drips for the deposits of a l1 sender in a rolling window of the default policy, e.g. 3/24h")
+
+	flag.StringVar(&KeyPath, "key", "key.txt", "comma separated private key paths, a path can be suffixed with :<min balance> to override the reserved balance of the wallet")
+	flag.StringVar(&KeyPassword, "key-password", "", "the password file to decrypt the keystore json keys")
+	flag.StringVar(&SignerEndpoint, "signer", "", "the external signer endpoint with the eth_signTransaction api, the -key items are the wallet addresses if provided")
 	flag.BoolVar(&OpenFaucet, "faucet", false, "open faucet or not")
+	flag.DurationVar(&DeferExpiry, "defer-expiry", time.Hour*72, "the deposits rejected for transient reasons are retried with backoff until it passes since the deposit, 0 ignores them at once")
+	flag.StringVar(&DryRun, "dry-run", "", "run the faucet in the dry-run mode with the shadow run name, the decisions are recorded in the shadow_drips table only")
+	flag.BoolVar(&OpenSync, "sync", true, "open data syncing or not, disable it to run another faucet for a different l2 chain")
 	flag.StringVar(&UniswapEndpoint, "uniswap-v3-graphql", "https://gateway.thegraph.com/api/subgraphs/id/5zvR82QoaXYFyDEKLZ9t6v9adgnptxYpKpSbxtgVENFV", "the uniswap v3 graphql endpoint")
 	flag.StringVar(&UniswapApiKey, "uniswap-v3-apikey", "", "the uniswap v3 graphql api key")
 	flag.DurationVar(&UniswapTimeout, "uniswap-timeout", time.Hour, "the uniswap token price cache timeout duration")
@@ -70,6 +132,24 @@ func main() {
 	if DripAmount <= 0 {
 		DripAmount = 0.01
 	}
+	if !services.ConfirmationMode(ConfirmationMode).Valid() {
+		logrus.Fatalf("invalid confirmation mode: %s", ConfirmationMode)
+	}
+	if SyncMode != "polling" && SyncMode != "streaming" {
+		logrus.Fatalf("invalid sync mode: %s", SyncMode)
+	}
+	if !services.FeeMode(FeeMode).Valid() {
+		logrus.Fatalf("invalid fee mode: %s", FeeMode)
+	}
+	if DisperseContract != "" && !common.IsHexAddress(DisperseContract) {
+		logrus.Fatalf("invalid disperse contract: %s", DisperseContract)
+	}
+	if BatchSize < 1 {
+		logrus.Fatalf("invalid batch size: %d", BatchSize)
+	}
+	if TipMultiplier <= 0 || FeeCapMultiplier <= 0 {
+		logrus.Fatalf("invalid fee multipliers: tip %f fee cap %f", TipMultiplier, FeeCapMultiplier)
+	}
 
 	// connect to db
 	db, err := repository.Connect(MysqlEndpoint)
@@ -87,11 +167,41 @@ func main() {
 		}
 	}()
 
-	l1rpc, err := ethclient.Dial(EtherEndpoint)
+	if flag.Arg(0) == "retry" {
+		if err := retry(basectx, repository.NewMetis(db), flag.Args()[1:]); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "decisions" {
+		if err := decisions(basectx, repository.NewMetis(db), flag.Args()[1:]); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+
+	var l1endpoints []string
+	if SyncMode == "streaming" && EtherWsEndpoint != "" {
+		// subscriptions go to the first endpoint supports them
+		l1endpoints = append(l1endpoints, EtherWsEndpoint)
+	}
+	for _, item := range strings.Split(EtherEndpoint, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			l1endpoints = append(l1endpoints, item)
+		}
+	}
+
+	if SyncMode == "streaming" && !slices.ContainsFunc(l1endpoints, supportsSubscription) {
+		logrus.Fatal("the streaming sync mode requires a websocket or ipc l1 endpoint, provide it with -l1ws")
+	}
+
+	l1rpc, err := multirpc.Dial(l1endpoints, EtherQuorum)
 	if err != nil {
 		logrus.Fatalf("unable to connect to l1 rpc: %s", err)
 	}
 	defer l1rpc.Close()
+	go l1rpc.Monitor(basectx, time.Second*30)
 
 	l1ChainId, err := l1rpc.ChainID(context.Background())
 	if err != nil {
@@ -102,27 +212,47 @@ func main() {
 		logrus.Fatalf("wrong layer1 network: %d", id)
 	}
 
+	bridgeAdddress := utils.MetisL1BridgeAddress(l1ChainId.Uint64())
+	bridge, err := goabi.NewL1StandardBridge(bridgeAdddress, l1rpc)
+	if err != nil {
+		logrus.Fatalf("unable to create bridge instance: %s", err)
+	}
+
+	syncer := &services.DataSync{
+		EtherClient:        l1rpc,
+		Repositroy:         repository.NewMetis(db),
+		Bridge:             bridge,
+		RangeSync:          RangeSyncNumber,
+		ConfirmationNumber: ConfirmationNumber,
+		ConfirmationMode:   services.ConfirmationMode(ConfirmationMode),
+		DripHeight:         DripHeight,
+		SyncWorkers:        SyncWorkers,
+	}
+
+	if flag.Arg(0) == "backfill" {
+		if err := backfill(basectx, syncer, flag.Args()[1:]); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+
 	eg, egctx := errgroup.WithContext(basectx)
 
 	// Data syncing service
 	eg.Go(func() error {
-		bridgeAdddress := utils.MetisL1BridgeAddress(l1ChainId.Uint64())
-		bridge, err := goabi.NewL1StandardBridge(bridgeAdddress, l1rpc)
-		if err != nil {
-			return fmt.Errorf("unable to create bridge instance: %s", err)
+		if !OpenSync {
+			return nil
 		}
 
-		syncer := &services.DataSync{
-			EtherClient:        l1rpc,
-			Repositroy:         repository.NewMetis(db),
-			Bridge:             bridge,
-			RangeSync:          RangeSyncNumber,
-			ConfirmationNumber: ConfirmationNumber,
-			DripHeight:         DripHeight,
-		}
 		if err := syncer.Prefight(egctx, StartFromHeight); err != nil {
 			return err
 		}
+		if SyncMode == "streaming" {
+			logrus.Info("streaming new events")
+			syncer.Stream(egctx)
+			return nil
+		}
+
 		logrus.Info("fetching new events")
 		timer := time.NewTimer(0)
 		for {
@@ -157,11 +287,34 @@ func main() {
 			return fmt.Errorf("wrong layer2 network: %d", id)
 		}
 
-		prvkey, wallet, err := utils.ReadPrvkey(KeyPath)
+		budgets, err := policy.ParseBudgets(Budget)
 		if err != nil {
-			return fmt.Errorf("unable to read pricate key: %s", err)
+			return fmt.Errorf("invalid -budget: %w", err)
+		}
+		policyBudgets, err := policy.ParseBudgets(PolicyBudget)
+		if err != nil {
+			return fmt.Errorf("invalid -policy-budget: %w", err)
+		}
+
+		recipientLimit, err := policy.ParseRateLimit(RecipientLimit)
+		if err != nil {
+			return fmt.Errorf("invalid -recipient-limit: %w", err)
+		}
+		senderLimit, err := policy.ParseRateLimit(SenderLimit)
+		if err != nil {
+			return fmt.Errorf("invalid -sender-limit: %w", err)
+		}
+
+		// the dry-run faucet never signs
+		var wallets []*services.Wallet
+		if DryRun == "" {
+			if wallets, err = readWallets(egctx, KeyPath, KeyPassword, SignerEndpoint); err != nil {
+				return err
+			}
+		}
+		for _, w := range wallets {
+			logrus.Infof("Hot wallet address is %s", w.Account)
 		}
-		logrus.Infof("Current wallet address is %s", wallet)
 
 		faucet := &services.Faucet{
 			EthClient:   l1rpc,
@@ -169,26 +322,44 @@ func main() {
 			Repositroy:  repository.NewMetis(db),
 			Uniswap:     utils.NewUniswap(UniswapEndpoint, UniswapApiKey, UniswapTimeout),
 			// uniswap doesn't have goerli subgraph
-			MetisL1Contract: utils.MetisL1TokenAddress(utils.EthMainnnetChainId),
-			Prvkey:          prvkey,
-			Account:         wallet,
-			Eip155Signer:    types.NewEIP155Signer(l2ChainId),
-			DefaultDrip:     utils.ToWei(DripAmount),
-			MaxDripUSD:      MaxDripUSD,
-			ReservedBalance: ReservedBalance,
+			MetisL1Contract:  utils.MetisL1TokenAddress(utils.EthMainnnetChainId),
+			L2ChainId:        l2ChainId.Uint64(),
+			RelayStartBlock:  RelayStartHeight,
+			RelayTimeout:     RelayTimeout,
+			Wallets:          wallets,
+			Signer:           types.LatestSignerForChainID(l2ChainId),
+			FeeMode:          services.FeeMode(FeeMode),
+			TipMultiplier:    TipMultiplier,
+			FeeCapMultiplier: FeeCapMultiplier,
+			StuckAge:         StuckAge,
+			MaxFee:           utils.ToWei(MaxFeeGwei / 1e9),
+			DisperseContract: common.HexToAddress(DisperseContract),
+			BatchSize:        BatchSize,
+			DefaultDrip:      utils.ToWei(DripAmount),
+			MaxDripUSD:       MaxDripUSD,
+			ReservedBalance:  ReservedBalance,
 			DripPolicies: []*policy.Drip{
 				{
-					Name:         "Default",
-					MatchAll:     true,
-					MatchToken:   nil,
-					CheckIfFirst: true,
-					CheckIfNoGas: true,
-					MinUSDEqual:  200,
-					StartTime:    time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC),
-					EndTime:      time.Date(2099, time.December, 31, 0, 0, 0, 0, time.UTC),
-					RebateType:   policy.DefaultRebateType,
+					Name:                   "Default",
+					MatchAll:               true,
+					MatchToken:             nil,
+					CheckIfFirst:           true,
+					CheckIfNoGas:           true,
+					MinUSDEqual:            200,
+					StartTime:              time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC),
+					EndTime:                time.Date(2099, time.December, 31, 0, 0, 0, 0, time.UTC),
+					RebateType:             policy.DefaultRebateType,
+					Budgets:                policyBudgets,
+					RecipientLimit:         recipientLimit,
+					SenderLimit:            senderLimit,
+					MaxRiskScore:           uint8(min(MaxRiskScore, 100)),
+					MaxRecipientsPerSender: MaxRecipients,
 				},
 			},
+			Budgets:     budgets,
+			TraceFunder: TraceFunder,
+			DryRun:      DryRun,
+			DeferExpiry: DeferExpiry,
 		}
 		if err := faucet.Initial(egctx); err != nil {
 			return err
@@ -216,3 +387,9 @@ func main() {
 		logrus.Fatal(err)
 	}
 }
+
+// supportsSubscription reports whether the rpc endpoint can serve subscriptions, only the http ones can't
+func supportsSubscription(endpoint string) bool {
+	endpoint = strings.ToLower(endpoint)
+	return !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://")
+}
@@ -0,0 +1,3 @@
+ALTER TABLE `deposits` DROP COLUMN `reorged`;
+
+DROP TABLE checkpoints;
@@ -0,0 +1,9 @@
+CREATE TABLE `checkpoints` (
+    `number` bigint UNSIGNED NOT NULL,
+    `blockhash` char(66) NOT NULL,
+    CONSTRAINT pk_number PRIMARY KEY (`number`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+
+INSERT INTO `checkpoints` (`number`, `blockhash`) SELECT `number`, `blockhash` FROM `height` WHERE `blockhash` <> '';
+
+ALTER TABLE `deposits` ADD COLUMN `reorged` tinyint NOT NULL DEFAULT 0 AFTER `status`;
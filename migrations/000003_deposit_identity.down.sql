@@ -0,0 +1,3 @@
+ALTER TABLE `deposits` DROP INDEX uk_txid_logindex, ADD INDEX idx_txid (`txid`);
+
+ALTER TABLE `deposits` DROP COLUMN `blockhash`, DROP COLUMN `logindex`;
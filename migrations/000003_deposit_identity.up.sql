@@ -0,0 +1,7 @@
+ALTER TABLE `deposits` ADD COLUMN `logindex` int UNSIGNED NOT NULL DEFAULT 0 AFTER `txid`, ADD COLUMN `blockhash` char(66) NOT NULL DEFAULT '' AFTER `height`;
+
+-- the log index of legacy rows is unknown, number them within the same transaction to keep them unique,
+-- and out of the real log index range, so the syncing can claim them with the real ones without colliding.
+UPDATE `deposits` AS A INNER JOIN (SELECT `id`, ROW_NUMBER() OVER (PARTITION BY `txid` ORDER BY `id`) - 1 AS `seq` FROM `deposits`) AS B ON A.`id` = B.`id` SET A.`logindex` = B.`seq` + 2147483648;
+
+ALTER TABLE `deposits` DROP INDEX idx_txid, ADD UNIQUE INDEX uk_txid_logindex (`txid`, `logindex`);
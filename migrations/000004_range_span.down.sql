@@ -0,0 +1 @@
+ALTER TABLE `height` DROP COLUMN `rangesync`;
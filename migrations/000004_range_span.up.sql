@@ -0,0 +1 @@
+ALTER TABLE `height` ADD COLUMN `rangesync` bigint UNSIGNED NOT NULL DEFAULT 0;
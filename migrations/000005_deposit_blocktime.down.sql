@@ -0,0 +1 @@
+ALTER TABLE `deposits` DROP COLUMN `blocktime`;
@@ -0,0 +1,6 @@
+ALTER TABLE `deposits` ADD COLUMN `blocktime` datetime NULL AFTER `blockhash`;
+
+-- the block time of legacy rows is unknown, the insert time is the closest one
+UPDATE `deposits` SET `blocktime` = `ctime`;
+
+ALTER TABLE `deposits` MODIFY COLUMN `blocktime` datetime NOT NULL;
@@ -0,0 +1,21 @@
+CREATE TABLE `withdrawals` (
+    `id` int UNSIGNED AUTO_INCREMENT,
+    `txid` char(66) NOT NULL,
+    `logindex` int UNSIGNED NOT NULL,
+    `chainid` bigint UNSIGNED NOT NULL,
+    `height` bigint UNSIGNED NOT NULL,
+    `blockhash` char(66) NOT NULL,
+    `blocktime` datetime NOT NULL,
+    `l1token` char(42) NOT NULL,
+    `l2token` char(42) NOT NULL,
+    `from` char(42) NOT NULL,
+    `to` char(42) NOT NULL,
+    `amount` decimal(64, 0) NOT NULL,
+    `ctime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_id PRIMARY KEY (`id`),
+    UNIQUE INDEX uk_txid_logindex (`txid`, `logindex`),
+    INDEX idx_from (`from`),
+    INDEX idx_to (`to`),
+    INDEX idx_l1token(`l1token`),
+    INDEX idx_height(`height`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
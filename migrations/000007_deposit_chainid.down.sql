@@ -0,0 +1 @@
+ALTER TABLE `deposits` DROP INDEX idx_chainid_status, DROP COLUMN `chainid`;
@@ -0,0 +1 @@
+ALTER TABLE `deposits` ADD COLUMN `chainid` bigint UNSIGNED NOT NULL DEFAULT 0 AFTER `logindex`, ADD INDEX idx_chainid_status (`chainid`, `status`);
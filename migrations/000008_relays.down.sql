@@ -0,0 +1,2 @@
+DROP TABLE relay_height;
+DROP TABLE relays;
@@ -0,0 +1,29 @@
+CREATE TABLE `relays` (
+    `id` int UNSIGNED AUTO_INCREMENT,
+    `chainid` bigint UNSIGNED NOT NULL,
+    `txid` char(66) NOT NULL,
+    `logindex` int UNSIGNED NOT NULL,
+    `height` bigint UNSIGNED NOT NULL,
+    `blocktime` datetime NOT NULL,
+    `l1token` char(42) NOT NULL,
+    `l2token` char(42) NOT NULL,
+    `from` char(42) NOT NULL,
+    `to` char(42) NOT NULL,
+    `amount` decimal(64, 0) NOT NULL,
+    `failed` tinyint(1) NOT NULL DEFAULT 0,
+    `pid` int UNSIGNED NOT NULL DEFAULT 0,
+    `latency` int UNSIGNED NOT NULL DEFAULT 0,
+    `checktime` datetime NULL,
+    `ctime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_id PRIMARY KEY (`id`),
+    UNIQUE INDEX uk_chainid_txid_logindex (`chainid`, `txid`, `logindex`),
+    INDEX idx_pid (`pid`),
+    INDEX idx_chainid_pid_blocktime (`chainid`, `pid`, `blocktime`),
+    INDEX idx_chainid_pid_checktime (`chainid`, `pid`, `checktime`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+
+CREATE TABLE `relay_height` (
+    `chainid` bigint UNSIGNED NOT NULL,
+    `number` bigint UNSIGNED NOT NULL,
+    CONSTRAINT pk_chainid PRIMARY KEY (`chainid`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
@@ -0,0 +1 @@
+ALTER TABLE `drips` ADD COLUMN `nonce` bigint UNSIGNED NULL AFTER `from`, ADD INDEX idx_from_nonce (`from`, `nonce`);
@@ -0,0 +1 @@
+DROP TABLE drip_txs;
@@ -0,0 +1,12 @@
+CREATE TABLE `drip_txs` (
+    `id` int UNSIGNED AUTO_INCREMENT,
+    `pid` bigint UNSIGNED NOT NULL,
+    `txid` char(66) NOT NULL,
+    `rawtx` blob NOT NULL,
+    `ctime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_id PRIMARY KEY (`id`),
+    UNIQUE INDEX uk_txid (`txid`),
+    INDEX idx_pid (`pid`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+
+INSERT INTO `drip_txs` (`pid`, `txid`, `rawtx`, `ctime`) SELECT `pid`, `txid`, `rawtx`, `ctime` FROM `drips`;
@@ -0,0 +1,3 @@
+ALTER TABLE `drip_txs` DROP COLUMN `retired`;
+
+ALTER TABLE `drips` DROP COLUMN `gasprice`, DROP COLUMN `gasused`, DROP COLUMN `blockhash`, DROP COLUMN `blocknumber`, DROP COLUMN `receiptstatus`;
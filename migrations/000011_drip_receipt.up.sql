@@ -0,0 +1,8 @@
+ALTER TABLE `drips`
+    ADD COLUMN `receiptstatus` tinyint UNSIGNED NULL AFTER `rawtx`,
+    ADD COLUMN `blocknumber` bigint UNSIGNED NULL AFTER `receiptstatus`,
+    ADD COLUMN `blockhash` char(66) NULL AFTER `blocknumber`,
+    ADD COLUMN `gasused` bigint UNSIGNED NULL AFTER `blockhash`,
+    ADD COLUMN `gasprice` decimal(64, 0) NULL AFTER `gasused`;
+
+ALTER TABLE `drip_txs` ADD COLUMN `retired` tinyint(1) NOT NULL DEFAULT 0 AFTER `rawtx`;
@@ -0,0 +1,5 @@
+ALTER TABLE `drip_txs` DROP INDEX idx_batchid, DROP COLUMN `batchid`;
+
+ALTER TABLE `drips` DROP INDEX idx_batchid, DROP COLUMN `batchid`;
+
+DROP TABLE drip_batches;
@@ -0,0 +1,21 @@
+CREATE TABLE `drip_batches` (
+    `id` int UNSIGNED AUTO_INCREMENT,
+    `chainid` bigint UNSIGNED NOT NULL,
+    `txid` char(66) NOT NULL,
+    `from` char(42) NOT NULL,
+    `nonce` bigint UNSIGNED NOT NULL,
+    `rawtx` blob NOT NULL,
+    `status` tinyint NOT NULL DEFAULT 0,
+    `receiptstatus` tinyint UNSIGNED NULL,
+    `blocknumber` bigint UNSIGNED NULL,
+    `blockhash` char(66) NULL,
+    `gasused` bigint UNSIGNED NULL,
+    `gasprice` decimal(64, 0) NULL,
+    `ctime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_id PRIMARY KEY (`id`),
+    INDEX idx_chainid_status (`chainid`, `status`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+
+ALTER TABLE `drips` ADD COLUMN `batchid` int UNSIGNED NOT NULL DEFAULT 0 AFTER `pid`, ADD INDEX idx_batchid (`batchid`);
+
+ALTER TABLE `drip_txs` ADD COLUMN `batchid` int UNSIGNED NOT NULL DEFAULT 0 AFTER `pid`, ADD INDEX idx_batchid (`batchid`);
@@ -0,0 +1,4 @@
+ALTER TABLE `drips`
+    DROP INDEX idx_ctime,
+    DROP COLUMN `usd`,
+    DROP COLUMN `policy`;
@@ -0,0 +1,4 @@
+ALTER TABLE `drips`
+    ADD COLUMN `policy` varchar(64) NOT NULL DEFAULT '' AFTER `amount`,
+    ADD COLUMN `usd` decimal(64, 20) NOT NULL DEFAULT 0 AFTER `policy`,
+    ADD INDEX idx_ctime (`ctime`);
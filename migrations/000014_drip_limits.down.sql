@@ -0,0 +1,3 @@
+ALTER TABLE `deposits` DROP INDEX idx_from;
+
+DROP TABLE drip_limit_locks;
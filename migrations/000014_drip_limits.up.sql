@@ -0,0 +1,7 @@
+CREATE TABLE `drip_limit_locks` (
+    `kind` tinyint UNSIGNED NOT NULL,
+    `address` char(42) NOT NULL,
+    CONSTRAINT pk_kind_address PRIMARY KEY (`kind`, `address`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+
+ALTER TABLE `deposits` ADD INDEX idx_from (`from`);
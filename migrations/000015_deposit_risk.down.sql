@@ -0,0 +1,6 @@
+ALTER TABLE `deposits`
+    DROP INDEX idx_funder_blocktime,
+    DROP INDEX idx_l1token_amount,
+    DROP INDEX idx_from_blocktime,
+    DROP COLUMN `funder`,
+    DROP COLUMN `riskscore`;
@@ -0,0 +1,6 @@
+ALTER TABLE `deposits`
+    ADD COLUMN `riskscore` tinyint UNSIGNED NULL AFTER `status`,
+    ADD COLUMN `funder` char(42) NULL AFTER `riskscore`,
+    ADD INDEX idx_from_blocktime (`from`, `blocktime`),
+    ADD INDEX idx_l1token_amount (`l1token`, `amount`),
+    ADD INDEX idx_funder_blocktime (`funder`, `blocktime`);
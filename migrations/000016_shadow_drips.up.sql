@@ -0,0 +1,13 @@
+CREATE TABLE `shadow_drips` (
+    `name` varchar(64) NOT NULL,
+    `pid` bigint UNSIGNED NOT NULL,
+    `policy` varchar(64) NOT NULL DEFAULT '',
+    `decision` tinyint UNSIGNED NOT NULL,
+    `reason` varchar(255) NOT NULL DEFAULT '',
+    `amount` decimal(64, 20) NOT NULL DEFAULT 0,
+    `usd` decimal(64, 20) NOT NULL DEFAULT 0,
+    `riskscore` tinyint UNSIGNED NULL,
+    `ctime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_name_pid PRIMARY KEY (`name`, `pid`),
+    INDEX idx_name_ctime (`name`, `ctime`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
@@ -0,0 +1,23 @@
+CREATE TABLE `decisions` (
+    `id` bigint UNSIGNED AUTO_INCREMENT,
+    `pid` bigint UNSIGNED NOT NULL,
+    `chainid` bigint UNSIGNED NOT NULL,
+    `txid` char(66) NOT NULL,
+    `from` char(42) NOT NULL,
+    `to` char(42) NOT NULL,
+    `policy` varchar(64) NOT NULL DEFAULT '',
+    `decision` tinyint UNSIGNED NOT NULL,
+    `reason` varchar(32) NOT NULL,
+    `message` varchar(255) NOT NULL DEFAULT '',
+    `checks` json NOT NULL,
+    `tokenprice` decimal(64, 20) NOT NULL DEFAULT 0,
+    `depositusd` decimal(64, 20) NOT NULL DEFAULT 0,
+    `dripamount` decimal(64, 20) NOT NULL DEFAULT 0,
+    `dripusd` decimal(64, 20) NOT NULL DEFAULT 0,
+    `ctime` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_id PRIMARY KEY (`id`),
+    INDEX idx_pid (`pid`),
+    INDEX idx_txid (`txid`),
+    INDEX idx_from (`from`),
+    INDEX idx_to (`to`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
@@ -0,0 +1,11 @@
+-- the deferred deposits are decided again
+UPDATE `deposits` SET `status` = 0 WHERE `status` = 5;
+
+ALTER TABLE `deposits`
+    DROP INDEX idx_chainid_status_nextretry,
+    DROP COLUMN `nextretry`,
+    DROP COLUMN `attempts`;
+
+ALTER TABLE `shadow_drips`
+    DROP COLUMN `nextretry`,
+    DROP COLUMN `attempts`;
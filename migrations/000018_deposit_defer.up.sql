@@ -0,0 +1,8 @@
+ALTER TABLE `deposits`
+    ADD COLUMN `attempts` smallint UNSIGNED NOT NULL DEFAULT 0 AFTER `riskscore`,
+    ADD COLUMN `nextretry` datetime NULL AFTER `attempts`,
+    ADD INDEX idx_chainid_status_nextretry (`chainid`, `status`, `nextretry`);
+
+ALTER TABLE `shadow_drips`
+    ADD COLUMN `attempts` smallint UNSIGNED NOT NULL DEFAULT 0 AFTER `riskscore`,
+    ADD COLUMN `nextretry` datetime NULL AFTER `attempts`;
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/metis-devops/metis-bridge-rebate/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// retry makes the deposits with reverted drips unprocessed again, the faucet will give them new drips
+func retry(ctx context.Context, repo repository.Metis, args []string) error {
+	var DepositId uint64
+
+	flagset := flag.NewFlagSet("retry", flag.ExitOnError)
+	flagset.Uint64Var(&DepositId, "id", 0, "the deposit id to retry, retries all the failed deposits if not provided")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	count, err := repo.RetryFailedDeposits(ctx, DepositId)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Retry failed deposits: %d", count)
+	return nil
+}
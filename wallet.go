@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/metis-devops/metis-bridge-rebate/internal/services"
+	"github.com/metis-devops/metis-bridge-rebate/internal/signer"
+	"github.com/metis-devops/metis-bridge-rebate/internal/utils"
+)
+
+// readWallets loads the hot wallets from the comma separated key items,
+// an item can be suffixed with ":<min balance>" to override the reserved balance of the wallet.
+// The items are the wallet addresses if the external signer is provided, otherwise they are the
+// raw hex key files or the keystore json files which are decrypted with the password file.
+func readWallets(ctx context.Context, keyItems, passwordPath, signerEndpoint string) ([]*services.Wallet, error) {
+	var wallets []*services.Wallet
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(keyItems, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var minBalance float64
+		key, rawMin, ok := strings.Cut(item, ":")
+		if ok {
+			value, err := strconv.ParseFloat(rawMin, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid min balance of %s: %w", key, err)
+			}
+			minBalance = value
+		}
+
+		txSigner, err := newSigner(ctx, key, passwordPath, signerEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		account := txSigner.Address()
+		if seen[account.Hex()] {
+			return nil, fmt.Errorf("duplicated wallet %s", account)
+		}
+		seen[account.Hex()] = true
+		wallets = append(wallets, &services.Wallet{Signer: txSigner, Account: account, MinBalance: minBalance})
+	}
+	if len(wallets) == 0 {
+		return nil, fmt.Errorf("no private key provided")
+	}
+	return wallets, nil
+}
+
+func newSigner(basectx context.Context, key, passwordPath, signerEndpoint string) (signer.Signer, error) {
+	if signerEndpoint != "" {
+		if !common.IsHexAddress(key) {
+			return nil, fmt.Errorf("invalid wallet address %s for the external signer", key)
+		}
+		newctx, cancel := context.WithTimeout(basectx, time.Second*10)
+		defer cancel()
+		return signer.NewExternal(newctx, signerEndpoint, common.HexToAddress(key))
+	}
+
+	isKeystore, err := utils.IsKeystore(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read private key %s: %w", key, err)
+	}
+
+	var prvkey *ecdsa.PrivateKey
+	if isKeystore {
+		if passwordPath == "" {
+			return nil, fmt.Errorf("the keystore %s requires the -key-password file", key)
+		}
+		prvkey, _, err = utils.ReadKeystore(key, passwordPath)
+	} else {
+		prvkey, _, err = utils.ReadPrvkey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read private key %s: %w", key, err)
+	}
+	return signer.NewLocal(prvkey), nil
+}